@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+)
+
+// newBlobStore returns the attachment store selected by BLOB_STORE: "local"
+// keeps files under ATTACHMENTS_DIR, "s3" uses an S3-compatible bucket such
+// as MinIO.
+func newBlobStore() storage.BlobStore {
+	switch kind := getenvLocal("BLOB_STORE", "local"); kind {
+	case "local":
+		store, err := storage.NewLocalStore(getenvLocal("ATTACHMENTS_DIR", "./data/attachments"))
+		if err != nil {
+			log.Fatalf("attachments dir: %v", err)
+		}
+		return store
+	case "s3":
+		store, err := storage.NewS3Store(context.Background(), storage.S3Config{
+			Endpoint:  getenvLocal("S3_ENDPOINT", "localhost:9000"),
+			PublicURL: getenvLocal("S3_PUBLIC_URL", ""),
+			AccessKey: getenvLocal("S3_ACCESS_KEY", ""),
+			SecretKey: getenvLocal("S3_SECRET_KEY", ""),
+			Bucket:    getenvLocal("S3_BUCKET", "attachments"),
+			Region:    getenvLocal("S3_REGION", "us-east-1"),
+			UseSSL:    getenvLocal("S3_USE_SSL", "false") == "true",
+		})
+		if err != nil {
+			log.Fatalf("s3 blob store: %v", err)
+		}
+		return store
+	default:
+		log.Fatalf("unknown BLOB_STORE %q", kind)
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// notebookChainSQL defines the CTE chain: notebook %s and every notebook
+// above it. UNION rather than UNION ALL keeps the walk finite even if a
+// cycle slipped in.
+const notebookChainSQL = `WITH RECURSIVE chain(id, parent_id, owner_id) AS (
+		SELECT b.id, b.parent_id, b.owner_id FROM notebooks b WHERE b.id = %s
+		UNION
+		SELECT b.id, b.parent_id, b.owner_id FROM notebooks b JOIN chain c ON b.id = c.parent_id
+	)`
+
+// noteVisibleSQL is the WHERE condition for notes the user may read: own
+// notes, shared notes, per-user grants, and notes in a notebook the user
+// owns or was granted, directly or through a parent notebook. Notes in the
+// trash are never visible.
+// The note table must be aliased as n and the user id bound to $1.
+var noteVisibleSQL = noteVisibleFor("$1")
+
+// noteAccessSQL is noteVisibleSQL without the trash check, for telling who
+// could read a note that was just moved to the trash.
+var noteAccessSQL = noteAccessFor("$1")
+
+// notebookGrantSQL tells whether the user bound to $1 reaches note n through
+// its notebook.
+var notebookGrantSQL = notebookGrantFor("$1")
+
+// noteVisibleFor is noteVisibleSQL for the user given by a SQL expression,
+// e.g. a column, to check many users in one query.
+func noteVisibleFor(user string) string {
+	return `(n.deleted_at IS NULL AND ` + noteAccessFor(user) + `)`
+}
+
+// noteAccessFor is noteAccessSQL for the user given by a SQL expression.
+func noteAccessFor(user string) string {
+	return `(n.owner_id = ` + user + `
+	OR n.shared = true
+	OR EXISTS (SELECT 1 FROM note_shares s WHERE s.note_id = n.id AND s.user_id = ` + user + `)
+	OR ` + notebookGrantFor(user) + `)`
+}
+
+// notebookGrantFor is notebookGrantSQL for the user given by a SQL expression.
+func notebookGrantFor(user string) string {
+	return `(n.notebook_id IS NOT NULL AND EXISTS (` + notebookChain("n.notebook_id") + `
+		SELECT 1 FROM chain c
+		WHERE c.owner_id = ` + user + `
+		   OR EXISTS (SELECT 1 FROM notebook_shares bs WHERE bs.notebook_id = c.id AND bs.user_id = ` + user + `)))`
+}
+
+// noteRole returns the strongest role userID holds on noteID: owner for the
+// note's owner, the granted role for a share, viewer for any note with the
+// shared flag, and the notebook role for notes filed in a notebook, capped at
+// editor so that only note owners can delete. It returns "" when the note
+// does not exist, is in the trash or the user has no access, so callers can
+// answer 404 in all cases.
+func noteRole(db *sql.DB, noteID, userID int) (string, error) {
+	var ownerID int
+	var shared bool
+	var grant sql.NullString
+	var notebookID sql.NullInt64
+	err := db.QueryRow(`SELECT n.owner_id, n.shared, s.role, n.notebook_id
+		FROM notes n
+		LEFT JOIN note_shares s ON s.note_id = n.id AND s.user_id = $2
+		WHERE n.id = $1 AND n.deleted_at IS NULL`, noteID, userID).Scan(&ownerID, &shared, &grant, &notebookID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if ownerID == userID {
+		return models.RoleOwner, nil
+	}
+	role := ""
+	if shared {
+		role = models.RoleViewer
+	}
+	if grant.Valid && models.RoleAtLeast(grant.String, role) {
+		role = grant.String
+	}
+	if notebookID.Valid && !models.RoleAtLeast(role, models.RoleEditor) {
+		nbRole, err := notebookRole(db, int(notebookID.Int64), userID)
+		if err != nil {
+			return "", err
+		}
+		if nbRole == models.RoleOwner {
+			nbRole = models.RoleEditor
+		}
+		if models.RoleAtLeast(nbRole, role) {
+			role = nbRole
+		}
+	}
+	return role, nil
+}
+
+// notebookRole returns the strongest role userID holds on notebookID or any
+// notebook above it: owner for the owner of any of them, otherwise the best
+// granted role. It returns "" when the notebook does not exist or the user
+// has no access.
+func notebookRole(db *sql.DB, notebookID, userID int) (string, error) {
+	rows, err := db.Query(notebookChain("$1")+`
+		SELECT CASE WHEN c.owner_id = $2 THEN 'owner' ELSE COALESCE(s.role, '') END
+		FROM chain c
+		LEFT JOIN notebook_shares s ON s.notebook_id = c.id AND s.user_id = $2`, notebookID, userID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	role := ""
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return "", err
+		}
+		if models.RoleAtLeast(r, role) {
+			role = r
+		}
+	}
+	return role, rows.Err()
+}
+
+// notebookChain returns notebookChainSQL starting at the given SQL expression.
+func notebookChain(start string) string {
+	return fmt.Sprintf(notebookChainSQL, start)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// handleArchive serves PUT and DELETE /api/notes/{id}/archive. Archiving
+// hides the note from everyone's default listing, so like sharing it is
+// left to the owner. It does not change the note's version or updated_at.
+func (h *NotesHandler) handleArchive(w http.ResponseWriter, r *http.Request, userID, noteID int, role string) {
+	var archivedAt string
+	switch r.Method {
+	case http.MethodPut:
+		archivedAt = `COALESCE(archived_at, now())`
+	case http.MethodDelete:
+		archivedAt = `NULL`
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if role != models.RoleOwner {
+		jsonError(w, "forbidden: only owner can archive", http.StatusForbidden)
+		return
+	}
+
+	if _, err := h.DB.Exec(`UPDATE notes SET archived_at=`+archivedAt+` WHERE id=$1`, noteID); err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publishNoteEvent(eventUpdated, noteID)
+	h.respondNote(w, userID, noteID, role, http.StatusOK)
+}
+
+// HandleBulkArchive serves POST /api/notes/archive, archiving every note the
+// user owns that has not been updated for olderThanDays days.
+func (h *NotesHandler) HandleBulkArchive(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.ArchiveReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.OlderThanDays < 1 {
+		jsonError(w, "olderThanDays must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.DB.Query(`UPDATE notes SET archived_at=now()
+		WHERE owner_id=$1 AND archived_at IS NULL AND deleted_at IS NULL
+		  AND updated_at < now() - make_interval(days => $2)
+		RETURNING id`, userID, req.OlderThanDays)
+	if err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The update is committed once every row has been read.
+	for _, id := range ids {
+		publishNoteEvent(eventUpdated, id)
+	}
+	jsonResponse(w, map[string]int{"archived": len(ids)}, http.StatusOK)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+)
+
+// maxAttachmentSize is the largest file that can be attached to a note.
+const maxAttachmentSize = 10 << 20
+
+// presignExpiry is how long presigned upload and download URLs stay valid.
+const presignExpiry = 15 * time.Minute
+
+// allowedAttachmentTypes lists the content types accepted for upload, as
+// sniffed from the file itself rather than trusted from the client. SVG and
+// HTML are left out because browsers would run scripts in them.
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+// inlineAttachmentTypes may be shown in the browser; others are downloaded.
+var inlineAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type AttachmentsHandler struct {
+	DB    *sql.DB
+	Blobs storage.BlobStore
+}
+
+// handleAttachments serves /api/notes/{id}/attachments: readers list them,
+// editors upload new ones as multipart/form-data with a "file" field, or
+// through /presign straight to an object store.
+func (h *NotesHandler) handleAttachments(w http.ResponseWriter, r *http.Request, userID, noteID int, role string, rest []string) {
+	if len(rest) == 1 && rest[0] == "presign" {
+		h.presignUpload(w, r, userID, noteID, role)
+		return
+	}
+	if len(rest) > 0 {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`SELECT `+attachmentColumns+` FROM attachments a
+			WHERE a.note_id=$1 AND NOT a.pending ORDER BY a.created_at, a.id`, noteID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		attachments := []models.Attachment{}
+		for rows.Next() {
+			a, _, err := scanAttachment(rows)
+			if err == nil {
+				attachments = append(attachments, a)
+			}
+		}
+		jsonResponse(w, attachments, http.StatusOK)
+
+	case http.MethodPost:
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
+		h.uploadAttachment(w, r, userID, noteID)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *NotesHandler) uploadAttachment(w http.ResponseWriter, r *http.Request, userID, noteID int) {
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		jsonError(w, "expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	var part io.Reader
+	var filename string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		if p.FormName() == "file" {
+			part, filename = p, p.FileName()
+			break
+		}
+	}
+	if part == nil {
+		jsonError(w, "file required", http.StatusBadRequest)
+		return
+	}
+	filename = cleanFilename(filename)
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		uploadError(w, err)
+		return
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedAttachmentTypes[contentType] {
+		jsonError(w, "file type not allowed: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key, err := attachmentKey(noteID)
+	if err != nil {
+		jsonError(w, "upload failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body := &countingReader{r: io.LimitReader(io.MultiReader(bytes.NewReader(head), part), maxAttachmentSize+1)}
+	if err := h.Blobs.Put(r.Context(), key, body, -1, contentType); err != nil {
+		uploadError(w, err)
+		return
+	}
+	if body.n > maxAttachmentSize {
+		h.deleteBlob(key)
+		jsonError(w, "file too large: max "+strconv.Itoa(maxAttachmentSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var id int
+	err = h.DB.QueryRow(`INSERT INTO attachments (note_id, uploader_id, filename, content_type, size, storage_key, thumb_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		noteID, userID, filename, contentType, body.n, key, initialThumbStatus(contentType)).Scan(&id)
+	if err != nil {
+		h.deleteBlob(key)
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wakeThumbnailWorker()
+
+	a, _, err := loadAttachment(h.DB, id)
+	if err != nil {
+		jsonError(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, a, http.StatusCreated)
+}
+
+// HandleAttachmentByID serves /api/attachments/{id}. Anyone who can read the
+// attachment's note may download it, with Range support; editors may delete
+// it. Attachments of notes the user cannot read answer 404.
+func (h *AttachmentsHandler) HandleAttachmentByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/api/attachments/"):], "/"), "/")
+	attachmentID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		jsonError(w, "invalid attachment id", http.StatusBadRequest)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "complete" {
+		h.completeUpload(w, r, userID, attachmentID)
+		return
+	}
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "thumb") {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	a, key, err := loadAttachment(h.DB, attachmentID)
+	if err == sql.ErrNoRows {
+		jsonError(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	role, err := noteRole(h.DB, a.NoteID, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		jsonError(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	if len(parts) == 2 {
+		h.serveThumbnail(w, r, a, key)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		disposition := "attachment"
+		if inlineAttachmentTypes[a.ContentType] {
+			disposition = "inline"
+		}
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})
+
+		// Object stores serve the bytes (and Range requests) themselves.
+		if p, ok := h.Blobs.(storage.Presigner); ok {
+			u, err := p.PresignGet(r.Context(), key, a.ContentType, disposition, presignExpiry)
+			if err != nil {
+				jsonError(w, "download failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Cache-Control", "private, no-store")
+			http.Redirect(w, r, u, http.StatusFound)
+			return
+		}
+
+		blob, err := h.Blobs.Open(r.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) {
+			jsonError(w, "attachment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			jsonError(w, "download failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer blob.Close()
+
+		w.Header().Set("Content-Type", a.ContentType)
+		w.Header().Set("Content-Disposition", disposition)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private")
+		http.ServeContent(w, r, a.Filename, a.CreatedAt, blob)
+
+	case http.MethodDelete:
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
+		if _, err := h.DB.Exec(`DELETE FROM attachments WHERE id=$1`, attachmentID); err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := deleteAttachmentBlobs(r.Context(), h.Blobs, key); err != nil {
+			log.Printf("delete blob %s: %v", key, err)
+		}
+		jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PurgeOrphanAttachments removes the blobs and rows of attachments whose
+// note was permanently deleted or whose direct upload was never completed,
+// returning how many were removed. Rows whose blobs cannot be deleted are
+// kept for the next run.
+func PurgeOrphanAttachments(db *sql.DB, blobs storage.BlobStore) (int, error) {
+	rows, err := db.Query(`SELECT id, storage_key FROM attachments
+		WHERE note_id IS NULL OR (pending AND created_at < now() - interval '1 day') LIMIT 500`)
+	if err != nil {
+		return 0, err
+	}
+	type orphan struct {
+		id  int
+		key string
+	}
+	var orphans []orphan
+	for rows.Next() {
+		var o orphan
+		if err := rows.Scan(&o.id, &o.key); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orphans = append(orphans, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, o := range orphans {
+		if err := deleteAttachmentBlobs(context.Background(), blobs, o.key); err != nil {
+			log.Printf("delete blob %s: %v", o.key, err)
+			continue
+		}
+		if _, err := db.Exec(`DELETE FROM attachments WHERE id=$1`, o.id); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+const attachmentColumns = `a.id, a.note_id, a.uploader_id, a.filename, a.content_type, a.size, a.storage_key, a.thumb_status, a.created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAttachment reads attachmentColumns, returning the storage key apart
+// since it is never sent to clients.
+func scanAttachment(row rowScanner) (models.Attachment, string, error) {
+	var a models.Attachment
+	var key string
+	err := row.Scan(&a.ID, &a.NoteID, &a.UploaderID, &a.Filename, &a.ContentType, &a.Size, &key, &a.ThumbStatus, &a.CreatedAt)
+	a.URL = "/api/attachments/" + strconv.Itoa(a.ID)
+	if a.ThumbStatus == thumbReady {
+		a.ThumbURL = a.URL + "/thumb"
+	}
+	return a, key, err
+}
+
+// loadAttachment returns an uploaded attachment that still belongs to a note.
+func loadAttachment(db *sql.DB, id int) (models.Attachment, string, error) {
+	return scanAttachment(db.QueryRow(`SELECT `+attachmentColumns+` FROM attachments a
+		WHERE a.id=$1 AND a.note_id IS NOT NULL AND NOT a.pending`, id))
+}
+
+func (h *NotesHandler) deleteBlob(key string) {
+	if err := h.Blobs.Delete(context.Background(), key); err != nil {
+		log.Printf("delete blob %s: %v", key, err)
+	}
+}
+
+// attachmentKey returns a fresh, unguessable storage key for a note.
+func attachmentKey(noteID int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "notes/" + strconv.Itoa(noteID) + "/" + hex.EncodeToString(b), nil
+}
+
+// cleanFilename keeps the base name of an uploaded file, falling back to
+// "file" and capping its length.
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	return name
+}
+
+// uploadError answers 413 when the body exceeded its limit and 400 for any
+// other read error.
+func uploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		jsonError(w, "file too large: max "+strconv.Itoa(maxAttachmentSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	jsonError(w, "upload failed: "+err.Error(), http.StatusBadRequest)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+const maxCommentLength = 10000
+
+const commentColumns = `c.id, c.note_id, c.parent_id, c.author_id, COALESCE(a.username, ''), c.body,
+	c.anchor_start, c.anchor_end, COALESCE(c.anchor_text, ''), c.resolved_at, COALESCE(rb.username, ''),
+	c.deleted_at IS NOT NULL, c.created_at, c.updated_at`
+
+const commentFrom = `FROM note_comments c
+	LEFT JOIN users a ON a.id = c.author_id
+	LEFT JOIN users rb ON rb.id = c.resolved_by`
+
+// handleComments serves /api/notes/{id}/comments[/{commentId}[/resolve]].
+// Anyone who can read the note can read and add comments. Only the author
+// edits or deletes a comment; the thread's author and the note's editors
+// can resolve it.
+func (h *NotesHandler) handleComments(w http.ResponseWriter, r *http.Request, userID, noteID int, role string, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			h.listComments(w, r, noteID)
+		case http.MethodPost:
+			h.createComment(w, r, userID, noteID)
+		default:
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	commentID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		jsonError(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+	var authorID sql.NullInt64
+	var parentID sql.NullInt64
+	var deleted bool
+	err = h.DB.QueryRow(`SELECT author_id, parent_id, deleted_at IS NOT NULL FROM note_comments
+		WHERE id=$1 AND note_id=$2`, commentID, noteID).Scan(&authorID, &parentID, &deleted)
+	if err == sql.ErrNoRows || (err == nil && deleted) {
+		jsonError(w, "comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	isAuthor := authorID.Valid && int(authorID.Int64) == userID
+
+	if len(rest) == 2 && rest[1] == "resolve" {
+		// POST resolves, DELETE reopens. Only whole threads are resolved.
+		if parentID.Valid {
+			jsonError(w, "only top-level comments can be resolved", http.StatusBadRequest)
+			return
+		}
+		if !isAuthor && !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			_, err = h.DB.Exec(`UPDATE note_comments
+				SET resolved_at=COALESCE(resolved_at, now()), resolved_by=CASE WHEN resolved_at IS NULL THEN $2 ELSE resolved_by END
+				WHERE id=$1`, commentID, userID)
+		case http.MethodDelete:
+			_, err = h.DB.Exec(`UPDATE note_comments SET resolved_at=NULL, resolved_by=NULL WHERE id=$1`, commentID)
+		default:
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.respondComment(w, noteID, commentID, http.StatusOK)
+		return
+	}
+	if len(rest) > 1 {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.respondComment(w, noteID, commentID, http.StatusOK)
+
+	case http.MethodPatch, http.MethodPut:
+		if !isAuthor {
+			jsonError(w, "forbidden: only the author can edit", http.StatusForbidden)
+			return
+		}
+		var req models.CommentReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if !validateCommentBody(w, &req) {
+			return
+		}
+		var oldBody string
+		err := h.DB.QueryRow(`UPDATE note_comments c SET body=$1, updated_at=now()
+			FROM note_comments old WHERE c.id=$2 AND old.id=c.id RETURNING old.body`, req.Body, commentID).Scan(&oldBody)
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := notifyCommentMentions(h.DB, noteID, commentID, userID, req.Body, oldBody); err != nil {
+			log.Printf("notify mentions in comment %d: %v", commentID, err)
+		}
+		h.respondComment(w, noteID, commentID, http.StatusOK)
+
+	case http.MethodDelete:
+		if !isAuthor {
+			jsonError(w, "forbidden: only the author can delete", http.StatusForbidden)
+			return
+		}
+		if err := deleteComment(h.DB, commentID); err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listComments returns the note's threads oldest first, each with its
+// replies nested. ?resolved=true|false filters threads.
+func (h *NotesHandler) listComments(w http.ResponseWriter, r *http.Request, noteID int) {
+	resolved := r.URL.Query().Get("resolved")
+	if resolved != "" && resolved != "true" && resolved != "false" {
+		jsonError(w, "resolved must be true or false", http.StatusBadRequest)
+		return
+	}
+
+	var content string
+	if err := h.DB.QueryRow(`SELECT COALESCE(content, '') FROM notes WHERE id=$1`, noteID).Scan(&content); err != nil {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+	rows, err := h.DB.Query(`SELECT `+commentColumns+` `+commentFrom+`
+		WHERE c.note_id=$1 ORDER BY c.created_at, c.id`, noteID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var all []models.Comment
+	for rows.Next() {
+		if c, err := scanComment(rows); err == nil {
+			all = append(all, c)
+		}
+	}
+
+	threads := []models.Comment{}
+	for _, c := range commentTree(all) {
+		if resolved != "" && c.Resolved != (resolved == "true") {
+			continue
+		}
+		locateAnchor(&c, content)
+		threads = append(threads, c)
+	}
+	jsonResponse(w, threads, http.StatusOK)
+}
+
+func (h *NotesHandler) createComment(w http.ResponseWriter, r *http.Request, userID, noteID int) {
+	var req models.CommentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if !validateCommentBody(w, &req) {
+		return
+	}
+
+	var start, end *int
+	var quote *string
+	if req.ParentID != nil {
+		if req.Anchor != nil {
+			jsonError(w, "replies cannot be anchored", http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		err := h.DB.QueryRow(`SELECT deleted_at IS NULL FROM note_comments WHERE id=$1 AND note_id=$2`, *req.ParentID, noteID).Scan(&ok)
+		if err == sql.ErrNoRows || (err == nil && !ok) {
+			jsonError(w, "parent comment not found", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.Anchor != nil {
+		var content string
+		if err := h.DB.QueryRow(`SELECT COALESCE(content, '') FROM notes WHERE id=$1`, noteID).Scan(&content); err != nil {
+			jsonError(w, "note not found", http.StatusNotFound)
+			return
+		}
+		text, ok := utf16Range(content, req.Anchor.Start, req.Anchor.End)
+		if !ok {
+			jsonError(w, "anchor is outside the note content", http.StatusBadRequest)
+			return
+		}
+		start, end, quote = &req.Anchor.Start, &req.Anchor.End, &text
+	}
+
+	var commentID int
+	err := h.DB.QueryRow(`INSERT INTO note_comments (note_id, parent_id, author_id, body, anchor_start, anchor_end, anchor_text)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		noteID, req.ParentID, userID, req.Body, start, end, quote).Scan(&commentID)
+	if err != nil {
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := notifyCommentMentions(h.DB, noteID, commentID, userID, req.Body, ""); err != nil {
+		log.Printf("notify mentions in comment %d: %v", commentID, err)
+	}
+	h.respondComment(w, noteID, commentID, http.StatusCreated)
+}
+
+// respondComment writes one comment with its replies.
+func (h *NotesHandler) respondComment(w http.ResponseWriter, noteID, commentID int, status int) {
+	var content string
+	if err := h.DB.QueryRow(`SELECT COALESCE(content, '') FROM notes WHERE id=$1`, noteID).Scan(&content); err != nil {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+	// The comment and every comment below it, parents before children.
+	rows, err := h.DB.Query(`WITH RECURSIVE sub(id) AS (
+			SELECT id FROM note_comments WHERE id=$1 AND note_id=$2
+			UNION
+			SELECT nc.id FROM note_comments nc JOIN sub ON nc.parent_id = sub.id
+		)
+		SELECT `+commentColumns+` `+commentFrom+`
+		WHERE c.id IN (SELECT id FROM sub) ORDER BY c.created_at, c.id`, commentID, noteID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var all []models.Comment
+	for rows.Next() {
+		if c, err := scanComment(rows); err == nil {
+			all = append(all, c)
+		}
+	}
+	for _, c := range commentTree(all) {
+		if c.ID == commentID {
+			locateAnchor(&c, content)
+			jsonResponse(w, c, status)
+			return
+		}
+	}
+	jsonError(w, "comment not found", http.StatusNotFound)
+}
+
+// deleteComment removes a comment, or blanks it while replies remain.
+// Blanked ancestors left without replies are removed as well.
+func deleteComment(db *sql.DB, commentID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id := commentID
+	for {
+		var hasReplies, deleted bool
+		var parentID sql.NullInt64
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM note_comments WHERE parent_id = c.id),
+				c.deleted_at IS NOT NULL, c.parent_id
+			FROM note_comments c WHERE c.id=$1 FOR UPDATE`, id).Scan(&hasReplies, &deleted, &parentID)
+		if err != nil {
+			return err
+		}
+		if hasReplies {
+			if id == commentID {
+				_, err = tx.Exec(`UPDATE note_comments SET body='', deleted_at=now(), updated_at=now() WHERE id=$1`, id)
+				if err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if id != commentID && !deleted {
+			break
+		}
+		if _, err := tx.Exec(`DELETE FROM note_comments WHERE id=$1`, id); err != nil {
+			return err
+		}
+		if !parentID.Valid {
+			break
+		}
+		id = int(parentID.Int64)
+	}
+	return tx.Commit()
+}
+
+func validateCommentBody(w http.ResponseWriter, req *models.CommentReq) bool {
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		jsonError(w, "body required", http.StatusBadRequest)
+		return false
+	}
+	if utf8.RuneCountInString(req.Body) > maxCommentLength {
+		jsonError(w, "body too long: max "+strconv.Itoa(maxCommentLength)+" characters", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func scanComment(row rowScanner) (models.Comment, error) {
+	var c models.Comment
+	var start, end sql.NullInt64
+	var quote string
+	err := row.Scan(&c.ID, &c.NoteID, &c.ParentID, &c.AuthorID, &c.AuthorUsername, &c.Body,
+		&start, &end, &quote, &c.ResolvedAt, &c.ResolvedBy, &c.Deleted, &c.CreatedAt, &c.UpdatedAt)
+	if start.Valid && end.Valid {
+		c.Anchor = &models.CommentAnchor{Start: int(start.Int64), End: int(end.Int64), Text: quote}
+	}
+	c.Resolved = c.ResolvedAt != nil
+	c.Replies = []models.Comment{}
+	return c, err
+}
+
+// commentTree nests comments under their parents and returns the top-level
+// ones. Input must list parents before their replies.
+func commentTree(all []models.Comment) []models.Comment {
+	children := map[int][]int{}
+	var roots []int
+	for i, c := range all {
+		if c.ParentID == nil {
+			roots = append(roots, i)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], i)
+		}
+	}
+	var build func(i int) models.Comment
+	build = func(i int) models.Comment {
+		c := all[i]
+		for _, j := range children[c.ID] {
+			c.Replies = append(c.Replies, build(j))
+		}
+		return c
+	}
+	// A reply fetched without its parent, as by respondComment, is a root.
+	ids := map[int]bool{}
+	for _, c := range all {
+		ids[c.ID] = true
+	}
+	for i, c := range all {
+		if c.ParentID != nil && !ids[*c.ParentID] {
+			roots = append(roots, i)
+		}
+	}
+	tree := make([]models.Comment, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i))
+	}
+	return tree
+}
+
+// locateAnchor follows an anchored comment through edits: when the quoted
+// text moved, the range is updated to its first occurrence, and when it is
+// gone the anchor is marked detached.
+func locateAnchor(c *models.Comment, content string) {
+	a := c.Anchor
+	if a == nil {
+		return
+	}
+	if text, ok := utf16Range(content, a.Start, a.End); ok && text == a.Text {
+		return
+	}
+	u := utf16.Encode([]rune(content))
+	q := utf16.Encode([]rune(a.Text))
+	for i := 0; len(q) > 0 && i+len(q) <= len(u); i++ {
+		if slices.Equal(u[i:i+len(q)], q) {
+			a.Start, a.End = i, i+len(q)
+			return
+		}
+	}
+	a.Detached = true
+}
+
+// utf16Range returns content[start:end] with offsets in UTF-16 code units.
+func utf16Range(content string, start, end int) (string, bool) {
+	u := utf16.Encode([]rune(content))
+	if start < 0 || end <= start || end > len(u) {
+		return "", false
+	}
+	return string(utf16.Decode(u[start:end])), true
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/lib/pq"
+)
+
+// Note event types sent on /api/events. Clients treat created and updated
+// alike as an upsert; deleted removes the note from the page.
+const (
+	eventCreated = "created"
+	eventUpdated = "updated"
+	eventDeleted = "deleted"
+
+	// eventAccess is not sent as such: those of its Users who can no longer
+	// read the note are sent a deletion.
+	eventAccess = "access"
+)
+
+const (
+	// eventBuffer is how many events a slow stream may fall behind before it
+	// is closed; the client then reconnects and refetches.
+	eventBuffer = 64
+	// eventQueue is how many events may wait for the dispatcher.
+	eventQueue = 1024
+	// eventHeartbeat keeps proxies from closing idle streams.
+	eventHeartbeat = 25 * time.Second
+)
+
+// noteEvent says that a note changed. Users, when set, limits who receives
+// it, as for a grantee who was just given access.
+type noteEvent struct {
+	Type   string
+	NoteID int
+	Users  []int
+}
+
+type eventSubscriber struct {
+	userID int
+	frames chan []byte
+}
+
+// eventHub fans note events out to the open streams of this process. A
+// single dispatcher works out who may see each event and renders it once
+// per user, so an event costs the same however many streams are open.
+type eventHub struct {
+	mu    sync.Mutex
+	subs  map[*eventSubscriber]struct{}
+	queue chan noteEvent
+}
+
+var noteEvents = &eventHub{
+	subs:  map[*eventSubscriber]struct{}{},
+	queue: make(chan noteEvent, eventQueue),
+}
+
+// StartNoteEvents starts the dispatcher behind /api/events.
+func StartNoteEvents(db *sql.DB) {
+	h := &NotesHandler{DB: db}
+	go func() {
+		for ev := range noteEvents.queue {
+			if err := h.dispatchEvent(ev); err != nil {
+				log.Printf("note events: %s of note %d: %v", ev.Type, ev.NoteID, err)
+			}
+		}
+	}()
+}
+
+func (hub *eventHub) subscribe(userID int) *eventSubscriber {
+	s := &eventSubscriber{userID: userID, frames: make(chan []byte, eventBuffer)}
+	hub.mu.Lock()
+	hub.subs[s] = struct{}{}
+	hub.mu.Unlock()
+	return s
+}
+
+func (hub *eventHub) unsubscribe(s *eventSubscriber) {
+	hub.mu.Lock()
+	if _, ok := hub.subs[s]; ok {
+		delete(hub.subs, s)
+		close(s.frames)
+	}
+	hub.mu.Unlock()
+}
+
+// users returns the distinct users with an open stream.
+func (hub *eventHub) users() []int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	var users []int
+	for s := range hub.subs {
+		if !slices.Contains(users, s.userID) {
+			users = append(users, s.userID)
+		}
+	}
+	return users
+}
+
+// send never blocks: a stream whose buffer is full is dropped.
+func (hub *eventHub) send(userID int, frame []byte) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for s := range hub.subs {
+		if s.userID != userID {
+			continue
+		}
+		select {
+		case s.frames <- frame:
+		default:
+			delete(hub.subs, s)
+			close(s.frames)
+		}
+	}
+}
+
+// publishNoteEvent announces a committed change to a note. Call it after
+// the transaction commits, never inside it.
+func publishNoteEvent(typ string, noteID int, users ...int) {
+	select {
+	case noteEvents.queue <- noteEvent{Type: typ, NoteID: noteID, Users: users}:
+	default:
+		log.Printf("note events: queue full, dropped %s of note %d", typ, noteID)
+	}
+}
+
+// noteReaders maps notes to the users with an open stream who can read
+// them.
+type noteReaders map[int][]int
+
+// readersOf snapshots who can read the notes matching where, before a
+// change that may take access away; where may use $2 onwards. Pass the
+// snapshot to publishLostAccess once the change commits. Events are best
+// effort, so errors are only logged.
+func readersOf(db sqlRunner, where string, args ...interface{}) noteReaders {
+	users := noteEvents.users()
+	if len(users) == 0 {
+		return nil
+	}
+	rows, err := db.Query(`SELECT n.id, u.id FROM notes n, unnest($1::int[]) AS u(id)
+		WHERE (`+where+`) AND `+noteVisibleFor("u.id"), append([]interface{}{pq.Array(users)}, args...)...)
+	if err != nil {
+		log.Printf("note events: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	readers := noteReaders{}
+	for rows.Next() {
+		var noteID, userID int
+		if err := rows.Scan(&noteID, &userID); err != nil {
+			log.Printf("note events: %v", err)
+			return nil
+		}
+		readers[noteID] = append(readers[noteID], userID)
+	}
+	return readers
+}
+
+// inNotebookSQL is a readersOf condition for the notes in notebook $2 and
+// the notebooks below it.
+const inNotebookSQL = `n.notebook_id IN (WITH RECURSIVE tree(id) AS (
+		SELECT $2::int
+		UNION
+		SELECT b.id FROM notebooks b JOIN tree t ON b.parent_id = t.id
+	) SELECT id FROM tree)`
+
+// publishLostAccess sends note.deleted to the users in a readersOf
+// snapshot who can no longer read the note.
+func publishLostAccess(before noteReaders) {
+	for noteID, users := range before {
+		publishNoteEvent(eventAccess, noteID, users...)
+	}
+}
+
+// HandleEvents serves GET /api/events, a Server-Sent Events stream of
+// changes to notes the user can read. Each event is named note.created,
+// note.updated or note.deleted; its data carries the note id and, except
+// for deletions, the note as GET /api/notes/{id} returns it. A note the
+// user can no longer read, say because it was unshared, also arrives as
+// note.deleted. Events are not replayed, so clients refetch after
+// reconnecting.
+func (h *NotesHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// The stream outlives any server write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sub := noteEvents.subscribe(userID)
+	defer noteEvents.unsubscribe(sub)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		log.Printf("events for user %d: %v", userID, err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case frame, ok := <-sub.frames:
+			if !ok {
+				// Dropped for falling behind.
+				return
+			}
+			w.Write(frame)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// dispatchEvent sends ev to the users with an open stream who may see it.
+// Trashed notes are no longer visible, so a deletion goes to everyone who
+// could read the note before it was trashed.
+func (h *NotesHandler) dispatchEvent(ev noteEvent) error {
+	users := noteEvents.users()
+	if ev.Users != nil {
+		users = slices.DeleteFunc(users, func(u int) bool { return !slices.Contains(ev.Users, u) })
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	switch ev.Type {
+	case eventDeleted, eventAccess:
+		var recipients []int
+		var err error
+		if ev.Type == eventDeleted {
+			recipients, err = h.eventReaders(ev.NoteID, users, noteAccessFor("u.id"))
+		} else {
+			var readers []int
+			readers, err = h.eventReaders(ev.NoteID, users, noteVisibleFor("u.id"))
+			recipients = slices.DeleteFunc(users, func(u int) bool { return slices.Contains(readers, u) })
+		}
+		if err != nil {
+			return err
+		}
+		frame, err := eventFrame(eventDeleted, map[string]interface{}{"type": eventDeleted, "noteId": ev.NoteID})
+		if err != nil {
+			return err
+		}
+		for _, u := range recipients {
+			noteEvents.send(u, frame)
+		}
+		return nil
+	}
+
+	readers, err := h.eventReaders(ev.NoteID, users, noteVisibleFor("u.id"))
+	if err != nil || len(readers) == 0 {
+		return err
+	}
+	// The note is loaded once; only the role and the star differ per user.
+	n, err := h.loadNote(0, ev.NoteID, "")
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var favorites []int64
+	err = h.DB.QueryRow(`SELECT COALESCE(array_agg(user_id), '{}') FROM user_favorites
+		WHERE note_id=$1 AND user_id = ANY($2)`, ev.NoteID, pq.Array(readers)).Scan(pq.Array(&favorites))
+	if err != nil {
+		return err
+	}
+	for _, u := range readers {
+		role, err := noteRole(h.DB, ev.NoteID, u)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			continue
+		}
+		n.Role = role
+		n.Favorite = slices.Contains(favorites, int64(u))
+		frame, err := eventFrame(ev.Type, map[string]interface{}{"type": ev.Type, "noteId": ev.NoteID, "note": n})
+		if err != nil {
+			return err
+		}
+		noteEvents.send(u, frame)
+	}
+	return nil
+}
+
+// eventReaders returns those of users for whom the access condition, which
+// names the user u.id, holds on noteID.
+func (h *NotesHandler) eventReaders(noteID int, users []int, access string) ([]int, error) {
+	rows, err := h.DB.Query(`SELECT u.id FROM notes n, unnest($1::int[]) AS u(id)
+		WHERE n.id = $2 AND `+access, pq.Array(users), noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var readers []int
+	for rows.Next() {
+		var u int
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		readers = append(readers, u)
+	}
+	return readers, rows.Err()
+}
+
+// eventFrame renders one Server-Sent Event named note.{typ}.
+func eventFrame(typ string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("event: note.%s\ndata: %s\n\n", typ, data)), nil
+}
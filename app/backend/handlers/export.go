@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+type ExportHandler struct {
+	DB *sql.DB
+}
+
+// HandleExport serves GET /api/export: a ZIP with one Markdown file per
+// note the user owns, trash excluded, each starting with YAML front matter.
+// The archive is streamed, so a failure halfway can only be logged.
+func (h *ExportHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT n.id, n.title, COALESCE(n.content, ''), n.shared, `+favoriteSQL+`, `+tagsSQL+`, n.updated_at
+		FROM notes n
+		WHERE n.owner_id = $1 AND n.deleted_at IS NULL
+		ORDER BY n.id`, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="notes-export-`+time.Now().Format("20060102")+`.zip"`)
+	zw := zip.NewWriter(w)
+	for rows.Next() {
+		var n models.Note
+		if err := rows.Scan(&n.ID, &n.Title, &n.Content, &n.Shared, &n.Favorite, pq.Array(&n.Tags), &n.Updated); err != nil {
+			log.Printf("export notes of user %d: %v", userID, err)
+			return
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     exportFilename(n),
+			Method:   zip.Deflate,
+			Modified: n.Updated,
+		})
+		if err == nil {
+			err = writeNoteMarkdown(f, n)
+		}
+		if err != nil {
+			log.Printf("export notes of user %d: %v", userID, err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("export notes of user %d: %v", userID, err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("export notes of user %d: %v", userID, err)
+	}
+}
+
+var slugUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// exportFilename is a slug of the title suffixed with the id, which keeps
+// names unique when titles repeat.
+func exportFilename(n models.Note) string {
+	slug := strings.Trim(slugUnsafe.ReplaceAllString(strings.ToLower(n.Title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	if slug == "" {
+		slug = "note"
+	}
+	return slug + "-" + strconv.Itoa(n.ID) + ".md"
+}
+
+// writeNoteMarkdown writes front matter followed by the note content.
+// Strings use YAML double-quoted style, whose escapes are a superset of
+// what strconv.Quote produces.
+func writeNoteMarkdown(w io.Writer, n models.Note) error {
+	tags := make([]string, len(n.Tags))
+	for i, t := range n.Tags {
+		tags[i] = strconv.Quote(t)
+	}
+	_, err := fmt.Fprintf(w, "---\nid: %d\ntitle: %s\ntags: [%s]\nshared: %t\nfavorite: %t\nupdated_at: %s\n---\n\n%s",
+		n.ID, strconv.Quote(n.Title), strings.Join(tags, ", "), n.Shared, n.Favorite,
+		n.Updated.UTC().Format(time.RFC3339), n.Content)
+	if err == nil && n.Content != "" && !strings.HasSuffix(n.Content, "\n") {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// favoriteSQL tells whether the user bound to $1 starred note n.
+const favoriteSQL = `EXISTS (SELECT 1 FROM user_favorites f WHERE f.note_id = n.id AND f.user_id = $1)`
+
+// setFavorite stars or unstars a note for one user. Both directions are
+// idempotent.
+func setFavorite(tx *sql.Tx, userID, noteID int, on bool) error {
+	if on {
+		_, err := tx.Exec(`INSERT INTO user_favorites (user_id, note_id) VALUES ($1, $2)
+			ON CONFLICT (user_id, note_id) DO NOTHING`, userID, noteID)
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM user_favorites WHERE user_id=$1 AND note_id=$2`, userID, noteID)
+	return err
+}
+
+// handleFavorite serves PUT and DELETE /api/notes/{id}/favorite. Favorites
+// are private to each user, so anyone who can read the note may star it.
+func (h *NotesHandler) handleFavorite(w http.ResponseWriter, r *http.Request, userID, noteID int) {
+	var on bool
+	switch r.Method {
+	case http.MethodPut:
+		on = true
+	case http.MethodDelete:
+		on = false
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := setFavorite(tx, userID, noteID, on); err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]bool{"favorite": on}, http.StatusOK)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// maxImportSize is the largest file accepted by POST /api/import.
+const maxImportSize = 50 << 20
+
+// importProgressEvery is how many items are processed between progress
+// updates of a job.
+const importProgressEvery = 20
+
+type ImportHandler struct {
+	DB *sql.DB
+}
+
+// HandleImport serves /api/import. POST takes a ZIP of Markdown files, an
+// Evernote .enex file or Google Keep Takeout JSON (or a Takeout ZIP), as a
+// multipart "file" field or as the raw body, and answers 202 with a job
+// that creates the notes in the background. ?format=markdown|enex|keep
+// overrides detection. GET lists the user's recent import jobs.
+func (h *ImportHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`SELECT `+importJobColumns+` FROM import_jobs
+			WHERE user_id=$1 ORDER BY created_at DESC, id DESC LIMIT 20`, userID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		jobs := []models.ImportJob{}
+		for rows.Next() {
+			if j, err := scanImportJob(rows); err == nil {
+				jobs = append(jobs, j)
+			}
+		}
+		jsonResponse(w, jobs, http.StatusOK)
+
+	case http.MethodPost:
+		filename, data, err := readImportUpload(w, r)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				jsonError(w, "file too large: max "+strconv.Itoa(maxImportSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+				return
+			}
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format, err := detectImportFormat(r.URL.Query().Get("format"), filename, data)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items, err := parseImport(format, filename, data)
+		if errors.Is(err, errImportLimit) {
+			jsonError(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		job, err := scanImportJob(h.DB.QueryRow(`INSERT INTO import_jobs (user_id, format, total)
+			VALUES ($1, $2, $3) RETURNING `+importJobColumns, userID, format, len(items)))
+		if err != nil {
+			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		go runImport(h.DB, job.ID, userID, items)
+
+		w.Header().Set("Location", "/api/import/"+strconv.Itoa(job.ID))
+		jsonResponse(w, job, http.StatusAccepted)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleImportByID serves GET /api/import/{id}: progress and the error
+// report of one of the user's import jobs.
+func (h *ImportHandler) HandleImportByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jobID, err := strconv.Atoi(strings.Trim(r.URL.Path[len("/api/import/"):], "/"))
+	if err != nil {
+		jsonError(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := scanImportJob(h.DB.QueryRow(`SELECT `+importJobColumns+` FROM import_jobs
+		WHERE id=$1 AND user_id=$2`, jobID, userID))
+	if err == sql.ErrNoRows {
+		jsonError(w, "import job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, job, http.StatusOK)
+}
+
+// FailInterruptedImports marks jobs that were running when the server
+// stopped as failed; their goroutine is gone.
+func FailInterruptedImports(db *sql.DB) {
+	if _, err := db.Exec(`UPDATE import_jobs SET status='failed', finished_at=now() WHERE status='running'`); err != nil {
+		log.Printf("import jobs: %v", err)
+	}
+}
+
+// readImportUpload returns the uploaded file from a multipart "file" field
+// or the raw request body.
+func readImportUpload(w http.ResponseWriter, r *http.Request) (string, []byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err == nil && len(data) == 0 {
+			err = errors.New("file required")
+		}
+		return "", data, err
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", nil, err
+	}
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return "", nil, errors.New("file required")
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if p.FormName() == "file" {
+			data, err := io.ReadAll(io.LimitReader(p, maxImportSize+1))
+			if err == nil && len(data) > maxImportSize {
+				err = &http.MaxBytesError{Limit: maxImportSize}
+			}
+			return p.FileName(), data, err
+		}
+	}
+}
+
+// runImport creates the parsed notes one transaction at a time, through the
+// same insertNote as POST /api/notes, recording progress and failures.
+func runImport(db *sql.DB, jobID, userID int, items []importItem) {
+	imported, failed := 0, 0
+	report := []models.ImportError{}
+	for i, item := range items {
+		err := item.Err
+		if err == nil {
+			err = importNote(db, userID, item)
+		}
+		if err != nil {
+			failed++
+			report = append(report, models.ImportError{Item: item.Name, Error: err.Error()})
+		} else {
+			imported++
+		}
+		if (i+1)%importProgressEvery == 0 && i+1 < len(items) {
+			updateImportJob(db, jobID, "running", i+1, imported, failed, report)
+		}
+	}
+	updateImportJob(db, jobID, "done", len(items), imported, failed, report)
+}
+
+func importNote(db *sql.DB, userID int, item importItem) error {
+	n := item.Note
+	if err := prepareNote(&n); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertNote(tx, userID, &n); err != nil {
+		return err
+	}
+	if item.Archived {
+		if _, err := tx.Exec(`UPDATE notes SET archived_at=now() WHERE id=$1`, n.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	publishNoteEvent(eventCreated, n.ID)
+	return nil
+}
+
+func updateImportJob(db *sql.DB, jobID int, status string, processed, imported, failed int, report []models.ImportError) {
+	errs, _ := json.Marshal(report)
+	_, err := db.Exec(`UPDATE import_jobs
+		SET status=$1, processed=$2, imported=$3, failed=$4, errors=$5,
+			finished_at=CASE WHEN $1 = 'running' THEN NULL ELSE now() END
+		WHERE id=$6`, status, processed, imported, failed, string(errs), jobID)
+	if err != nil {
+		log.Printf("import job %d: %v", jobID, err)
+	}
+}
+
+const importJobColumns = `id, format, status, total, processed, imported, failed, errors, created_at, finished_at`
+
+func scanImportJob(row rowScanner) (models.ImportJob, error) {
+	var j models.ImportJob
+	var errs []byte
+	err := row.Scan(&j.ID, &j.Format, &j.Status, &j.Total, &j.Processed, &j.Imported, &j.Failed, &errs, &j.CreatedAt, &j.FinishedAt)
+	if err == nil {
+		err = json.Unmarshal(errs, &j.Errors)
+	}
+	return j, err
+}
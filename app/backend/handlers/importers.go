@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+const (
+	// maxImportItems caps the notes created by one import.
+	maxImportItems = 5000
+	// maxImportEntrySize caps a single file inside an imported ZIP.
+	maxImportEntrySize = 5 << 20
+	// maxImportUnzipped caps the bytes read out of all files in an imported
+	// ZIP together, so a small archive cannot expand without bound.
+	maxImportUnzipped = 100 << 20
+)
+
+// errImportLimit is wrapped by errors about imports over one of the limits
+// above, which are answered 413.
+var errImportLimit = errors.New("import too large")
+
+var errTooManyImportItems = fmt.Errorf("%w: max %d notes per import", errImportLimit, maxImportItems)
+
+// importItem is one note parsed from an import, or the reason it could not
+// be parsed. Name identifies it in the error report.
+type importItem struct {
+	Name     string
+	Note     models.Note
+	Archived bool
+	Err      error
+}
+
+// Import formats, as detected from the upload.
+const (
+	importZip  = "zip"
+	importENEX = "enex"
+	importKeep = "keep"
+)
+
+// detectImportFormat picks the format from an explicit choice, the file
+// extension, or the first bytes of the file.
+func detectImportFormat(format, filename string, data []byte) (string, error) {
+	switch format {
+	case importZip, importENEX, importKeep:
+		return format, nil
+	case "markdown":
+		return importZip, nil
+	case "":
+	default:
+		return "", errors.New("format must be markdown, enex or keep")
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".zip":
+		return importZip, nil
+	case ".enex", ".xml":
+		return importENEX, nil
+	case ".json":
+		return importKeep, nil
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return importZip, nil
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return importENEX, nil
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte("[")):
+		return importKeep, nil
+	}
+	return "", errors.New("unrecognized import file")
+}
+
+// parseImport turns an uploaded file into import items. An error means the
+// file as a whole could not be read; problems with single notes are
+// reported on their item.
+func parseImport(format, filename string, data []byte) ([]importItem, error) {
+	var items []importItem
+	var err error
+	switch format {
+	case importZip:
+		items, err = parseImportZip(data)
+	case importENEX:
+		items, err = parseENEX(bytes.NewReader(data))
+	case importKeep:
+		items, err = parseKeep(filename, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > maxImportItems {
+		return nil, errTooManyImportItems
+	}
+	return items, nil
+}
+
+// parseImportZip reads Markdown files with front matter and, as found in a
+// Takeout archive, Google Keep JSON files. Other files are ignored.
+func parseImportZip(data []byte) ([]importItem, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("invalid zip archive")
+	}
+	// Limits are checked as entries are read, before the archive is
+	// unpacked in full.
+	var items []importItem
+	var unzipped int64
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if f.FileInfo().IsDir() || (ext != ".md" && ext != ".markdown" && ext != ".json") {
+			continue
+		}
+		if len(items) >= maxImportItems {
+			return nil, errTooManyImportItems
+		}
+		b, err := readZipEntry(f)
+		if unzipped += int64(len(b)); unzipped > maxImportUnzipped {
+			return nil, fmt.Errorf("%w: max %d MB unpacked", errImportLimit, maxImportUnzipped>>20)
+		}
+		if err != nil {
+			items = append(items, importItem{Name: f.Name, Err: err})
+			continue
+		}
+		if ext == ".json" {
+			keep, err := parseKeep(f.Name, b)
+			if err != nil {
+				items = append(items, importItem{Name: f.Name, Err: err})
+			}
+			items = append(items, keep...)
+			continue
+		}
+		items = append(items, parseMarkdownNote(f.Name, string(b)))
+	}
+	return items, nil
+}
+
+// readZipEntry returns the contents of f. Whatever was unpacked is returned
+// with the error, so the caller can count it.
+func readZipEntry(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxImportEntrySize {
+		return nil, errors.New("file too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, maxImportEntrySize+1))
+	if err != nil {
+		return b, err
+	}
+	if len(b) > maxImportEntrySize {
+		return b, errors.New("file too large")
+	}
+	return b, nil
+}
+
+// parseMarkdownNote reads a Markdown file as written by the export: YAML
+// front matter with title, tags, shared, favorite and archived, then the
+// content. Without a title the first heading or the file name is used.
+func parseMarkdownNote(name, src string) importItem {
+	item := importItem{Name: name}
+	src = strings.TrimPrefix(strings.ReplaceAll(src, "\r\n", "\n"), "\ufeff")
+
+	if strings.HasPrefix(src, "---\n") {
+		end := strings.Index(src[4:], "\n---")
+		if end < 0 {
+			item.Err = errors.New("unterminated front matter")
+			return item
+		}
+		front := src[4 : 4+end]
+		src = strings.TrimPrefix(src[4+end+4:], "\n")
+		src = strings.TrimPrefix(src, "\n")
+		if err := applyFrontMatter(&item, front); err != nil {
+			item.Err = err
+			return item
+		}
+	}
+	item.Note.Content = src
+
+	if item.Note.Title == "" {
+		for _, line := range strings.Split(src, "\n") {
+			if strings.HasPrefix(line, "# ") {
+				item.Note.Title = strings.TrimSpace(line[2:])
+				break
+			}
+		}
+	}
+	if item.Note.Title == "" {
+		item.Note.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	return item
+}
+
+// applyFrontMatter understands the small YAML subset the export writes:
+// scalar keys and tags as a flow or block list. Unknown keys are ignored.
+func applyFrontMatter(item *importItem, front string) error {
+	lines := strings.Split(front, "\n")
+	for i := 0; i < len(lines); i++ {
+		key, value, ok := strings.Cut(lines[i], ":")
+		if !ok || strings.HasPrefix(lines[i], " ") || strings.HasPrefix(lines[i], "#") {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "title":
+			item.Note.Title, err = yamlScalar(value)
+		case "tags":
+			if value == "" {
+				// block list: following "- tag" lines
+				for i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "- ") {
+					i++
+					tag, err := yamlScalar(strings.TrimSpace(lines[i])[2:])
+					if err != nil {
+						return fmt.Errorf("tags: %v", err)
+					}
+					item.Note.Tags = append(item.Note.Tags, tag)
+				}
+			} else {
+				item.Note.Tags, err = yamlFlowList(value)
+			}
+		case "shared":
+			item.Note.Shared, err = strconv.ParseBool(value)
+		case "favorite":
+			item.Note.Favorite, err = strconv.ParseBool(value)
+		case "archived":
+			item.Archived, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: invalid value", key)
+		}
+	}
+	return nil
+}
+
+// yamlScalar unquotes a double-quoted, single-quoted or plain scalar.
+func yamlScalar(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		return strconv.Unquote(v)
+	case strings.HasPrefix(v, `'`):
+		if len(v) < 2 || !strings.HasSuffix(v, `'`) {
+			return "", errors.New("unterminated string")
+		}
+		return strings.ReplaceAll(v[1:len(v)-1], `''`, `'`), nil
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// yamlFlowList parses [a, "b", 'c'].
+func yamlFlowList(v string) ([]string, error) {
+	if !strings.HasPrefix(v, "[") || !strings.HasSuffix(v, "]") {
+		return nil, errors.New("expected a list")
+	}
+	v = strings.TrimSpace(v[1 : len(v)-1])
+	var out []string
+	for v != "" {
+		var raw string
+		switch v[0] {
+		case '"', '\'':
+			end := 1
+			for end < len(v) {
+				if v[0] == '"' && v[end] == '\\' {
+					end += 2
+					continue
+				}
+				if v[end] == v[0] {
+					if v[0] == '\'' && end+1 < len(v) && v[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end > len(v) {
+				end = len(v)
+			}
+			if end == len(v) {
+				return nil, errors.New("unterminated string")
+			}
+			raw, v = v[:end+1], v[end+1:]
+		default:
+			i := strings.Index(v, ",")
+			if i < 0 {
+				i = len(v)
+			}
+			raw, v = v[:i], v[i:]
+		}
+		s, err := yamlScalar(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+		v = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(v), ","))
+	}
+	return out, nil
+}
+
+type enexNote struct {
+	Title   string   `xml:"title"`
+	Content string   `xml:"content"`
+	Tags    []string `xml:"tag"`
+}
+
+var enMediaTag = regexp.MustCompile(`(?s)<en-media[^>]*/>|<en-media[^>]*>.*?</en-media>`)
+
+// parseENEX streams the notes of an Evernote export. The ENML body inside
+// <en-note> is HTML, which is how notes are stored here; embedded media is
+// dropped.
+func parseENEX(r io.Reader) ([]importItem, error) {
+	dec := xml.NewDecoder(r)
+	var items []importItem
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("invalid enex file: " + err.Error())
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		var n enexNote
+		name := "note " + strconv.Itoa(len(items)+1)
+		if err := dec.DecodeElement(&n, &start); err != nil {
+			return nil, errors.New("invalid enex file: " + err.Error())
+		}
+		item := importItem{Name: name}
+		item.Note.Title = strings.TrimSpace(n.Title)
+		if item.Note.Title != "" {
+			item.Name += " (" + item.Note.Title + ")"
+		}
+		item.Note.Tags = n.Tags
+		item.Note.Content, item.Err = enmlBody(n.Content)
+		items = append(items, item)
+	}
+	if items == nil {
+		return nil, errors.New("no notes found in enex file")
+	}
+	return items, nil
+}
+
+func enmlBody(enml string) (string, error) {
+	start := strings.Index(enml, "<en-note")
+	if start < 0 {
+		if strings.TrimSpace(enml) == "" {
+			return "", nil
+		}
+		return "", errors.New("content is not ENML")
+	}
+	open := strings.Index(enml[start:], ">")
+	if open < 0 {
+		return "", errors.New("content is not ENML")
+	}
+	body := enml[start+open+1:]
+	if strings.HasSuffix(enml[:start+open+1], "/>") {
+		return "", nil
+	}
+	if end := strings.LastIndex(body, "</en-note>"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(enMediaTag.ReplaceAllString(body, "")), nil
+}
+
+type keepNote struct {
+	Title       string `json:"title"`
+	TextContent string `json:"textContent"`
+	ListContent []struct {
+		Text      string `json:"text"`
+		IsChecked bool   `json:"isChecked"`
+	} `json:"listContent"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	IsTrashed  bool `json:"isTrashed"`
+	IsArchived bool `json:"isArchived"`
+	IsPinned   bool `json:"isPinned"`
+}
+
+// parseKeep reads Google Keep Takeout JSON: one note per file, or an array
+// of notes. Trashed notes are skipped, pinned ones become favorites and
+// checklists become Markdown task lists.
+func parseKeep(name string, data []byte) ([]importItem, error) {
+	var notes []keepNote
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &notes); err != nil {
+			return nil, errors.New("invalid keep json")
+		}
+	} else {
+		var n keepNote
+		if err := json.Unmarshal(trimmed, &n); err != nil {
+			return nil, errors.New("invalid keep json")
+		}
+		notes = []keepNote{n}
+	}
+
+	var items []importItem
+	for i, n := range notes {
+		if n.IsTrashed {
+			continue
+		}
+		item := importItem{Name: name, Archived: n.IsArchived}
+		if len(notes) > 1 {
+			item.Name += " #" + strconv.Itoa(i+1)
+		}
+		item.Note.Title = strings.TrimSpace(n.Title)
+		item.Note.Favorite = n.IsPinned
+		for _, l := range n.Labels {
+			item.Note.Tags = append(item.Note.Tags, l.Name)
+		}
+		content := n.TextContent
+		for _, li := range n.ListContent {
+			box := "[ ]"
+			if li.IsChecked {
+				box = "[x]"
+			}
+			content += "- " + box + " " + li.Text + "\n"
+		}
+		item.Note.Content = content
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import "testing"
+
+func TestYAMLFlowList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{in: `[a, b]`, want: []string{"a", "b"}},
+		{in: `[]`, want: nil},
+		{in: `[ ]`, want: nil},
+		{in: `[a, ]`, want: []string{"a"}},
+		{in: `["a, b", c]`, want: []string{"a, b", "c"}},
+		{in: `['it''s, ok', "x\"y"]`, want: []string{"it's, ok", `x"y`}},
+		{in: `[plain # comment]`, want: []string{"plain"}},
+		{in: `["a, b]`, wantErr: "unterminated string"},
+		{in: `['a]`, wantErr: "unterminated string"},
+		{in: `a, b`, wantErr: "expected a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := yamlFlowList(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("yamlFlowList(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !equalStrings(got, tt.want) {
+				t.Fatalf("yamlFlowList(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownNote(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		src      string
+		title    string
+		content  string
+		tags     []string
+		shared   bool
+		favorite bool
+		archived bool
+		wantErr  string
+	}{
+		{
+			name:     "front matter",
+			src:      "---\ntitle: \"Plan: Q3\"\ntags: [work, 'q3']\nshared: true\nfavorite: false\narchived: true\n---\n\nbody\n",
+			title:    "Plan: Q3",
+			content:  "body\n",
+			tags:     []string{"work", "q3"},
+			shared:   true,
+			archived: true,
+		},
+		{
+			name:    "quoted tags with commas",
+			src:     "---\ntitle: t\ntags: [\"a, b\", 'c, d']\n---\nbody",
+			title:   "t",
+			content: "body",
+			tags:    []string{"a, b", "c, d"},
+		},
+		{
+			name:    "empty tags",
+			src:     "---\ntitle: t\ntags: []\n---\nbody",
+			title:   "t",
+			content: "body",
+		},
+		{
+			name:     "block list tags",
+			src:      "---\ntitle: t\ntags:\n  - one\n  - \"two, three\"\nfavorite: true\n---\nbody",
+			title:    "t",
+			content:  "body",
+			tags:     []string{"one", "two, three"},
+			favorite: true,
+		},
+		{
+			name:    "missing closing delimiter",
+			src:     "---\ntitle: t\n\nbody\n",
+			wantErr: "unterminated front matter",
+		},
+		{
+			name:    "invalid flag",
+			src:     "---\nshared: maybe\n---\nbody",
+			wantErr: "shared: invalid value",
+		},
+		{
+			name:    "unterminated tag list item",
+			src:     "---\ntags: [\"a, b]\n---\nbody",
+			wantErr: "tags: invalid value",
+		},
+		{
+			name:    "CRLF",
+			src:     "---\r\ntitle: t\r\ntags: [a, \"b, c\"]\r\n---\r\n\r\nline one\r\nline two\r\n",
+			title:   "t",
+			content: "line one\nline two\n",
+			tags:    []string{"a", "b, c"},
+		},
+		{
+			name:    "byte order mark",
+			src:     "\ufeff---\ntitle: t\n---\nbody",
+			title:   "t",
+			content: "body",
+		},
+		{
+			name:    "title from heading",
+			src:     "intro\n# Heading \nbody",
+			title:   "Heading",
+			content: "intro\n# Heading \nbody",
+		},
+		{
+			name:    "title from file name",
+			file:    "notes/My Note.md",
+			src:     "body",
+			title:   "My Note",
+			content: "body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			if file == "" {
+				file = "note.md"
+			}
+			item := parseMarkdownNote(file, tt.src)
+			if tt.wantErr != "" {
+				if item.Err == nil || item.Err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", item.Err, tt.wantErr)
+				}
+				return
+			}
+			if item.Err != nil {
+				t.Fatalf("error = %v", item.Err)
+			}
+			n := item.Note
+			if n.Title != tt.title || n.Content != tt.content || !equalStrings(n.Tags, tt.tags) {
+				t.Errorf("note = %q, %q, %q; want %q, %q, %q", n.Title, n.Content, n.Tags, tt.title, tt.content, tt.tags)
+			}
+			if n.Shared != tt.shared || n.Favorite != tt.favorite || item.Archived != tt.archived {
+				t.Errorf("flags = shared %v, favorite %v, archived %v; want %v, %v, %v",
+					n.Shared, n.Favorite, item.Archived, tt.shared, tt.favorite, tt.archived)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// linkPasswordHeader carries the password of a protected share link, so it
+// never ends up in URLs or logged request bodies.
+const linkPasswordHeader = "X-Link-Password"
+
+const linkColumns = `l.id, l.note_id, l.created_by, l.password_hash IS NOT NULL, l.expires_at, l.max_views, l.views, l.revoked_at, l.created_at`
+
+type PublicHandler struct {
+	DB *sql.DB
+}
+
+// handleLinks serves /api/notes/{id}/links. Public links expose the note to
+// anyone holding the token, so like the shared flag they are managed by the
+// owner only.
+func (h *NotesHandler) handleLinks(w http.ResponseWriter, r *http.Request, userID, noteID int, role string, rest []string) {
+	if role != models.RoleOwner {
+		jsonError(w, "forbidden: only owner can manage links", http.StatusForbidden)
+		return
+	}
+
+	if len(rest) == 1 {
+		// DELETE /notes/{id}/links/{linkId} → revoke
+		linkID, err := strconv.Atoi(rest[0])
+		if err != nil {
+			jsonError(w, "invalid link id", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		res, err := h.DB.Exec(`UPDATE note_links SET revoked_at=COALESCE(revoked_at, now())
+			WHERE id=$1 AND note_id=$2`, linkID, noteID)
+		if err != nil {
+			jsonError(w, "revoke failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			jsonError(w, "link not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, map[string]string{"message": "revoked"}, http.StatusOK)
+		return
+	}
+	if len(rest) > 1 {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`SELECT `+linkColumns+` FROM note_links l
+			WHERE l.note_id=$1 ORDER BY l.created_at DESC, l.id DESC`, noteID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		links := []models.ShareLink{}
+		for rows.Next() {
+			if l, err := scanLink(rows); err == nil {
+				links = append(links, l)
+			}
+		}
+		jsonResponse(w, links, http.StatusOK)
+
+	case http.MethodPost:
+		var req models.LinkReq
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				jsonError(w, "invalid json", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			jsonError(w, "expiresAt must be in the future", http.StatusBadRequest)
+			return
+		}
+		if req.MaxViews != nil && *req.MaxViews < 1 {
+			jsonError(w, "maxViews must be at least 1", http.StatusBadRequest)
+			return
+		}
+		var passwordHash *string
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				jsonError(w, "server error", http.StatusInternalServerError)
+				return
+			}
+			s := string(hash)
+			passwordHash = &s
+		}
+
+		token, err := newLinkToken()
+		if err != nil {
+			jsonError(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresAt != nil {
+			t := req.ExpiresAt.UTC()
+			expiresAt = &t
+		}
+		l, err := scanLink(h.DB.QueryRow(`INSERT INTO note_links AS l
+				(note_id, created_by, token_hash, password_hash, expires_at, max_views)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+linkColumns, noteID, userID, hashLinkToken(token), passwordHash, expiresAt, req.MaxViews))
+		if err != nil {
+			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		l.Token = token
+		l.URL = "/api/public/" + token
+		jsonResponse(w, l, http.StatusCreated)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePublic serves GET /api/public/{token} without authentication. The
+// note is shown read-only while the link is neither revoked, expired nor out
+// of views; protected links need the password in X-Link-Password. Every
+// successful read counts as a view.
+func (h *PublicHandler) HandlePublic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.Trim(r.URL.Path[len("/api/public/"):], "/")
+	if token == "" {
+		jsonError(w, "link not found", http.StatusNotFound)
+		return
+	}
+
+	var linkID, noteID int
+	var passwordHash sql.NullString
+	var expiresAt sql.NullTime
+	var maxViews sql.NullInt64
+	var views int
+	err := h.DB.QueryRow(`SELECT l.id, l.note_id, l.password_hash, l.expires_at, l.max_views, l.views
+		FROM note_links l JOIN notes n ON n.id = l.note_id
+		WHERE l.token_hash=$1 AND l.revoked_at IS NULL AND n.deleted_at IS NULL`, hashLinkToken(token)).
+		Scan(&linkID, &noteID, &passwordHash, &expiresAt, &maxViews, &views)
+	if err == sql.ErrNoRows {
+		jsonError(w, "link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now().UTC()) {
+		jsonError(w, "link expired", http.StatusGone)
+		return
+	}
+	if maxViews.Valid && int64(views) >= maxViews.Int64 {
+		jsonError(w, "view limit reached", http.StatusGone)
+		return
+	}
+	if passwordHash.Valid {
+		password := r.Header.Get(linkPasswordHeader)
+		if password == "" {
+			jsonError(w, "password required", http.StatusUnauthorized)
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(passwordHash.String), []byte(password)) != nil {
+			jsonError(w, "invalid password", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// Count the view atomically so concurrent reads cannot exceed the limit.
+	res, err := h.DB.Exec(`UPDATE note_links SET views = views + 1
+		WHERE id=$1 AND (max_views IS NULL OR views < max_views)`, linkID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		jsonError(w, "view limit reached", http.StatusGone)
+		return
+	}
+
+	var n models.PublicNote
+	var version int
+	err = h.DB.QueryRow(`SELECT n.title, COALESCE(n.content, ''), `+tagsSQL+`, u.username, n.updated_at, n.version
+		FROM notes n JOIN users u ON u.id = n.owner_id WHERE n.id=$1`, noteID).
+		Scan(&n.Title, &n.Content, pq.Array(&n.Tags), &n.OwnerUsername, &n.Updated, &version)
+	if err != nil {
+		jsonError(w, "link not found", http.StatusNotFound)
+		return
+	}
+	if n.HTML, err = noteHTML(noteID, version, n.Content); err != nil {
+		jsonError(w, "render failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	jsonResponse(w, n, http.StatusOK)
+}
+
+func scanLink(row rowScanner) (models.ShareLink, error) {
+	var l models.ShareLink
+	err := row.Scan(&l.ID, &l.NoteID, &l.CreatedBy, &l.HasPassword, &l.ExpiresAt, &l.MaxViews, &l.Views, &l.RevokedAt, &l.CreatedAt)
+	return l, err
+}
+
+// newLinkToken returns 256 random bits, URL-safe encoded.
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashLinkToken is what is stored and looked up, so a database leak does
+// not reveal working links.
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
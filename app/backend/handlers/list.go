@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+// noteQuery collects WHERE conditions and their positional arguments for
+// the notes listing. The requesting user is always $1.
+type noteQuery struct {
+	conds []string
+	args  []interface{}
+}
+
+func newNoteQuery(userID int) *noteQuery {
+	return &noteQuery{args: []interface{}{userID}}
+}
+
+// arg binds v and returns its placeholder.
+func (q *noteQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+func (q *noteQuery) where(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+func (q *noteQuery) whereSQL() string {
+	return strings.Join(q.conds, " AND ")
+}
+
+const (
+	defaultNotesLimit = 50
+	maxNotesLimit     = 100
+)
+
+// sortKey is a sortable column of the notes listing. Cursors carry the key
+// as text, cast back to typ for the keyset comparison.
+type sortKey struct {
+	expr string
+	typ  string
+	desc bool // default direction
+}
+
+var noteSortKeys = map[string]sortKey{
+	"updated_at": {expr: `n.updated_at`, typ: `timestamp`, desc: true},
+	"title":      {expr: `lower(n.title)`, typ: `text`},
+	"id":         {expr: `n.id`, typ: `integer`, desc: true},
+}
+
+// noteCursor marks the last row of a page. Sort and Desc guard against a
+// cursor being replayed with a different ordering.
+type noteCursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	Key  string `json:"k"`
+	ID   int    `json:"i"`
+}
+
+func (c noteCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeNoteCursor(s string) (noteCursor, error) {
+	var c noteCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// notePage is the envelope of GET /api/notes.
+type notePage struct {
+	Notes      []models.NoteListItem `json:"notes"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// listNotes serves GET /api/notes: own notes, shared notes and notes shared
+// with me, newest first, one page at a time.
+//
+//	view=mine|shared                own notes only, or only notes shared with me
+//	notebook=ID|none                notes filed directly in a notebook, or in none
+//	q=...                           full-text search, ranked, with an escaped HTML
+//	                                snippet marking matches in <mark>
+//	archived=true|false|all         archived notes are left out unless asked for
+//	owner, favorite, shared         exact filters
+//	tag=a&tag=b, tag_mode=all|any   notes with all (default) or any of the tags
+//	updated_after/_before           RFC 3339 timestamp or YYYY-MM-DD
+//	sort=updated_at|title|id|rank   with order=asc|desc
+//	limit, cursor                   keyset pagination on (sort key, id)
+//	include=content                 full content instead of an excerpt
+func (h *NotesHandler) listNotes(w http.ResponseWriter, r *http.Request, userID int) {
+	params := r.URL.Query()
+	q := newNoteQuery(userID)
+
+	switch params.Get("view") {
+	case "":
+		q.where(noteVisibleSQL)
+	case "mine":
+		q.where(`n.deleted_at IS NULL AND n.owner_id = $1`)
+	case "shared":
+		q.where(`n.deleted_at IS NULL AND n.owner_id <> $1 AND (EXISTS (SELECT 1 FROM note_shares s WHERE s.note_id = n.id AND s.user_id = $1)
+			OR ` + notebookGrantSQL + `)`)
+	default:
+		jsonError(w, "invalid view", http.StatusBadRequest)
+		return
+	}
+
+	if err := addNoteFilters(q, params); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sortName := params.Get("sort")
+	keys := noteSortKeys
+	extra := `'', 0::real`
+	if search := strings.TrimSpace(params.Get("q")); search != "" {
+		tsq := `websearch_to_tsquery('simple', ` + q.arg(search) + `)`
+		q.where(`n.search @@ ` + tsq)
+		// Matches are delimited with control characters, stripped from the
+		// text beforehand, and become <mark> once the text is escaped.
+		extra = `ts_headline('simple', translate(regexp_replace(COALESCE(n.content, ''), '<[^>]*>', ' ', 'g'), E'\x01\x02', ''), ` + tsq + `,
+				E'StartSel=\x01, StopSel=\x02, MaxFragments=2, MaxWords=20, MinWords=5'),
+			ts_rank(n.search, ` + tsq + `)`
+		keys = map[string]sortKey{"rank": {expr: `ts_rank(n.search, ` + tsq + `)::float8`, typ: `float8`, desc: true}}
+		for name, k := range noteSortKeys {
+			keys[name] = k
+		}
+		if sortName == "" {
+			sortName = "rank"
+		}
+	}
+	if sortName == "" {
+		sortName = "updated_at"
+	}
+	key, ok := keys[sortName]
+	if !ok {
+		jsonError(w, "invalid sort", http.StatusBadRequest)
+		return
+	}
+	desc := key.desc
+	switch params.Get("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		jsonError(w, "invalid order", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultNotesLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxNotesLimit {
+			jsonError(w, "limit must be between 1 and "+strconv.Itoa(maxNotesLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+	if v := params.Get("cursor"); v != "" {
+		c, err := decodeNoteCursor(v)
+		if err != nil || c.Sort != sortName || c.Desc != desc {
+			jsonError(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		q.where(`(` + key.expr + `, n.id) ` + cmp + ` (` + q.arg(c.Key) + `::` + key.typ + `, ` + q.arg(c.ID) + `)`)
+	}
+
+	content := `left(regexp_replace(COALESCE(n.content, ''), '<[^>]*>', ' ', 'g'), 200), ''`
+	if params.Get("include") == "content" {
+		content = `'', COALESCE(n.content, '')`
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT n.id, n.owner_id, u.username, n.notebook_id, n.title, `+content+`, n.shared, `+favoriteSQL+`, `+tagsSQL+`, n.version, n.updated_at, n.archived_at,
+			`+extra+`, (`+key.expr+`)::text
+		FROM notes n
+		JOIN users u ON u.id = n.owner_id
+		WHERE `+q.whereSQL()+`
+		ORDER BY `+key.expr+` `+dir+`, n.id `+dir+`
+		LIMIT `+strconv.Itoa(limit+1), q.args...)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	page := notePage{Notes: []models.NoteListItem{}}
+	var last noteCursor
+	for rows.Next() {
+		var item models.NoteListItem
+		n := &item.Note
+		var sortValue string
+		if err := rows.Scan(&n.ID, &n.OwnerID, &n.OwnerUsername, &n.NotebookID, &n.Title, &n.Excerpt, &item.Content, &n.Shared, &n.Favorite, pq.Array(&n.Tags), &n.Version, &n.Updated, &n.ArchivedAt,
+			&n.Snippet, &n.Rank, &sortValue); err != nil {
+			continue
+		}
+		if len(page.Notes) == limit {
+			page.NextCursor = last.encode()
+			break
+		}
+		n.Snippet = markSnippet(n.Snippet)
+		page.Notes = append(page.Notes, item)
+		last = noteCursor{Sort: sortName, Desc: desc, Key: sortValue, ID: n.ID}
+	}
+	jsonResponse(w, page, http.StatusOK)
+}
+
+// markSnippet turns a ts_headline result into safe HTML: the text is
+// escaped and only the match delimiters become <mark> tags.
+func markSnippet(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "\x01", "<mark>")
+	return strings.ReplaceAll(s, "\x02", "</mark>")
+}
+
+// addNoteFilters adds the archive, exact-match and date-range filters of
+// the notes listing.
+func addNoteFilters(q *noteQuery, params url.Values) error {
+	switch params.Get("archived") {
+	case "", "false":
+		q.where(`n.archived_at IS NULL`)
+	case "true":
+		q.where(`n.archived_at IS NOT NULL`)
+	case "all":
+	default:
+		return errors.New("archived must be true, false or all")
+	}
+	if v := params.Get("notebook"); v == "none" {
+		q.where(`n.notebook_id IS NULL`)
+	} else if v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("notebook must be a notebook id or none")
+		}
+		q.where(`n.notebook_id = ` + q.arg(id))
+	}
+	if v := params.Get("owner"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("owner must be a user id")
+		}
+		q.where(`n.owner_id = ` + q.arg(id))
+	}
+	for name, expr := range map[string]string{"favorite": favoriteSQL, "shared": `n.shared`} {
+		if v := params.Get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return errors.New(name + " must be true or false")
+			}
+			q.where(expr + ` = ` + q.arg(b))
+		}
+	}
+	if tags := params["tag"]; len(tags) > 0 {
+		tags, err := normalizeTags(tags)
+		if err != nil {
+			return err
+		}
+		switch params.Get("tag_mode") {
+		case "", "all":
+			q.where(`(SELECT COUNT(*) FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.note_id = n.id AND t.name = ANY(` + q.arg(pq.Array(tags)) + `)) = ` + strconv.Itoa(len(tags)))
+		case "any":
+			q.where(`EXISTS (SELECT 1 FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.note_id = n.id AND t.name = ANY(` + q.arg(pq.Array(tags)) + `))`)
+		default:
+			return errors.New("tag_mode must be all or any")
+		}
+	}
+	for name, cmp := range map[string]string{"updated_after": ">=", "updated_before": "<"} {
+		if v := params.Get(name); v != "" {
+			t, err := parseDateParam(v)
+			if err != nil {
+				return errors.New(name + " must be an RFC 3339 timestamp or YYYY-MM-DD")
+			}
+			q.where(`n.updated_at ` + cmp + ` ` + q.arg(t.UTC()))
+		}
+	}
+	return nil
+}
+
+func parseDateParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"container/list"
+	"regexp"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// markdown renders CommonMark with the GFM extensions: tables, task lists,
+// strikethrough and autolinks. Raw HTML is passed through because the editor
+// stores HTML; htmlPolicy strips anything unsafe afterwards.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// htmlPolicy is the allowlist rendered notes are sanitized against: the
+// usual user-generated-content elements plus the disabled checkboxes of
+// task lists and language classes on code blocks.
+var htmlPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return p
+}()
+
+// renderMarkdown converts note content to sanitized HTML.
+func renderMarkdown(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return htmlPolicy.Sanitize(buf.String()), nil
+}
+
+// maxRenderCacheEntries bounds the rendered HTML kept in memory.
+const maxRenderCacheEntries = 1000
+
+type renderKey struct {
+	noteID, version int
+}
+
+type renderEntry struct {
+	key  renderKey
+	html string
+}
+
+// renderCache keeps recently rendered notes, keyed by note and version so
+// an edit never serves stale HTML. The least recently used entry is dropped
+// when the cache is full.
+var renderCache = struct {
+	sync.Mutex
+	order   *list.List
+	entries map[renderKey]*list.Element
+}{order: list.New(), entries: map[renderKey]*list.Element{}}
+
+// noteHTML returns the rendered content of a note version, rendering it on
+// a cache miss.
+func noteHTML(noteID, version int, content string) (string, error) {
+	key := renderKey{noteID, version}
+
+	renderCache.Lock()
+	if e, ok := renderCache.entries[key]; ok {
+		renderCache.order.MoveToFront(e)
+		html := e.Value.(*renderEntry).html
+		renderCache.Unlock()
+		return html, nil
+	}
+	renderCache.Unlock()
+
+	html, err := renderMarkdown(content)
+	if err != nil {
+		return "", err
+	}
+
+	renderCache.Lock()
+	defer renderCache.Unlock()
+	if _, ok := renderCache.entries[key]; !ok {
+		renderCache.entries[key] = renderCache.order.PushFront(&renderEntry{key, html})
+		if renderCache.order.Len() > maxRenderCacheEntries {
+			oldest := renderCache.order.Back()
+			renderCache.order.Remove(oldest)
+			delete(renderCache.entries, oldest.Value.(*renderEntry).key)
+		}
+	}
+	return html, nil
+}
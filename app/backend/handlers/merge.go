@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxDiffCells bounds the line comparisons of a single diff; memory stays
+// linear in the input. Larger inputs are treated as one changed block,
+// which still merges when only one side touched it and conflicts otherwise.
+const maxDiffCells = 4_000_000
+
+const (
+	markerCurrent  = "<<<<<<< current\n"
+	markerSplit    = "=======\n"
+	markerIncoming = ">>>>>>> incoming\n"
+)
+
+// hunk replaces base[start:end] with lines on one side of a merge.
+type hunk struct {
+	side       int
+	start, end int
+	lines      []string
+}
+
+// merge3 performs a line-level three-way merge of current and incoming,
+// which both derive from base. Changes from either side are combined; where
+// both sides changed overlapping lines differently the result holds git
+// style conflict markers and conflict is true.
+func merge3(base, current, incoming string) (merged string, conflict bool) {
+	if current == incoming {
+		return current, false
+	}
+	if base == current {
+		return incoming, false
+	}
+	if base == incoming {
+		return current, false
+	}
+
+	b := splitLines(base)
+	hunks := append(diffHunks(b, splitLines(current), 0), diffHunks(b, splitLines(incoming), 1)...)
+	sort.SliceStable(hunks, func(i, j int) bool {
+		if hunks[i].start != hunks[j].start {
+			return hunks[i].start < hunks[j].start
+		}
+		return hunks[i].end < hunks[j].end
+	})
+
+	var out strings.Builder
+	pos := 0
+	for i := 0; i < len(hunks); {
+		start, end := hunks[i].start, hunks[i].end
+		j := i + 1
+		for j < len(hunks) && overlaps(start, end, hunks[j]) {
+			if hunks[j].end > end {
+				end = hunks[j].end
+			}
+			j++
+		}
+		group := hunks[i:j]
+		i = j
+
+		writeLines(&out, b[pos:start])
+		pos = end
+
+		ours := applyHunks(b, start, end, group, 0)
+		theirs := applyHunks(b, start, end, group, 1)
+		switch {
+		case !touches(group, 1) || equalStrings(ours, theirs):
+			writeLines(&out, ours)
+		case !touches(group, 0):
+			writeLines(&out, theirs)
+		default:
+			conflict = true
+			out.WriteString(markerCurrent)
+			writeLines(&out, ensureTrailingNewline(ours))
+			out.WriteString(markerSplit)
+			writeLines(&out, ensureTrailingNewline(theirs))
+			out.WriteString(markerIncoming)
+		}
+	}
+	writeLines(&out, b[pos:])
+	return out.String(), conflict
+}
+
+// overlaps reports whether h collides with the group spanning base[start:end].
+// Edits touching the same boundary collide when either is a pure insertion,
+// because their order would be ambiguous.
+func overlaps(start, end int, h hunk) bool {
+	if h.start < end {
+		return true
+	}
+	return h.start == end && (h.start == h.end || start == end)
+}
+
+func touches(group []hunk, side int) bool {
+	for _, h := range group {
+		if h.side == side {
+			return true
+		}
+	}
+	return false
+}
+
+// applyHunks returns base[start:end] as rewritten by one side's hunks.
+func applyHunks(base []string, start, end int, group []hunk, side int) []string {
+	var out []string
+	pos := start
+	for _, h := range group {
+		if h.side != side {
+			continue
+		}
+		out = append(out, base[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, base[pos:end]...)
+}
+
+// diffHunks lists the changes turning base into other, using the longest
+// common subsequence of lines.
+func diffHunks(base, other []string, side int) []hunk {
+	// Common prefix and suffix never take part in a change.
+	pre := 0
+	for pre < len(base) && pre < len(other) && base[pre] == other[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(base)-pre && suf < len(other)-pre && base[len(base)-1-suf] == other[len(other)-1-suf] {
+		suf++
+	}
+	a := base[pre : len(base)-suf]
+	o := other[pre : len(other)-suf]
+	if len(a) == 0 && len(o) == 0 {
+		return nil
+	}
+	if len(a)*len(o) > maxDiffCells || len(a) == 0 || len(o) == 0 {
+		return []hunk{{side: side, start: pre, end: pre + len(a), lines: o}}
+	}
+
+	var hunks []hunk
+	i, j := 0, 0
+	for _, m := range lcsPairs(a, o, 0, 0, nil) {
+		if m[0] > i || m[1] > j {
+			hunks = append(hunks, hunk{side: side, start: pre + i, end: pre + m[0], lines: o[j:m[1]]})
+		}
+		i, j = m[0]+1, m[1]+1
+	}
+	if i < len(a) || j < len(o) {
+		hunks = append(hunks, hunk{side: side, start: pre + i, end: pre + len(a), lines: o[j:]})
+	}
+	return hunks
+}
+
+// lcsPairs appends to out the index pairs, offset by ai and oi, of a
+// longest common subsequence of a and o. It splits a in half and finds
+// where the subsequence crosses o from LCS lengths computed towards the
+// middle from both ends (Hirschberg), so it needs only two rows at a time
+// rather than a len(a)×len(o) table.
+func lcsPairs(a, o []string, ai, oi int, out [][2]int) [][2]int {
+	if len(a) == 0 || len(o) == 0 {
+		return out
+	}
+	if len(a) == 1 {
+		for j := range o {
+			if o[j] == a[0] {
+				return append(out, [2]int{ai, oi + j})
+			}
+		}
+		return out
+	}
+	mid := len(a) / 2
+	fwd := lcsPrefixRow(a[:mid], o)
+	bwd := lcsSuffixRow(a[mid:], o)
+	split, best := 0, -1
+	for j := range fwd {
+		if n := fwd[j] + bwd[j]; n > best {
+			split, best = j, n
+		}
+	}
+	out = lcsPairs(a[:mid], o[:split], ai, oi, out)
+	return lcsPairs(a[mid:], o[split:], ai+mid, oi+split, out)
+}
+
+// lcsPrefixRow returns row[j], the LCS length of a and o[:j].
+func lcsPrefixRow(a, o []string) []int {
+	prev, cur := make([]int, len(o)+1), make([]int, len(o)+1)
+	for i := range a {
+		for j := 1; j <= len(o); j++ {
+			if a[i] == o[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// lcsSuffixRow returns row[j], the LCS length of a and o[j:].
+func lcsSuffixRow(a, o []string) []int {
+	prev, cur := make([]int, len(o)+1), make([]int, len(o)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(o) - 1; j >= 0; j-- {
+			if a[i] == o[j] {
+				cur[j] = prev[j+1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// splitLines splits s after each newline, so joining the result restores s.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureTrailingNewline keeps conflict markers on their own line when a
+// side ends without a newline.
+func ensureTrailingNewline(lines []string) []string {
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		out := append([]string(nil), lines...)
+		out[n-1] += "\n"
+		return out
+	}
+	return lines
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n distinct lines, with line i replaced by edits[i].
+func numberedLines(n int, edits map[int]string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if line, ok := edits[i]; ok {
+			b.WriteString(line + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func conflictBlock(current, incoming string) string {
+	return markerCurrent + current + markerSplit + incoming + markerIncoming
+}
+
+func TestMerge3(t *testing.T) {
+	// Past maxDiffCells the current side becomes one block spanning the
+	// whole text, so the unrelated incoming edit collides with it.
+	bigBase := numberedLines(3000, nil)
+	bigCurrent := numberedLines(3000, map[int]string{0: "first", 2999: "last"})
+	bigIncoming := numberedLines(3000, map[int]string{1500: "middle"})
+
+	tests := []struct {
+		name                    string
+		base, current, incoming string
+		want                    string
+		wantConflict            bool
+	}{
+		{
+			name:     "disjoint edits",
+			base:     "a\nb\nc\n",
+			current:  "A\nb\nc\n",
+			incoming: "a\nb\nC\n",
+			want:     "A\nb\nC\n",
+		},
+		{
+			name:         "overlapping edits",
+			base:         "a\nb\nc\n",
+			current:      "a\nB1\nc\n",
+			incoming:     "a\nB2\nc\n",
+			want:         "a\n" + conflictBlock("B1\n", "B2\n") + "c\n",
+			wantConflict: true,
+		},
+		{
+			name:     "identical edits on both sides",
+			base:     "a\nb\nc\n",
+			current:  "A\nb\nc\n",
+			incoming: "A\nb\nC\n",
+			want:     "A\nb\nC\n",
+		},
+		{
+			name:     "same text on both sides",
+			base:     "a\n",
+			current:  "b\n",
+			incoming: "b\n",
+			want:     "b\n",
+		},
+		{
+			name:         "inserts at the same boundary",
+			base:         "a\nb\n",
+			current:      "a\nx\nb\n",
+			incoming:     "a\ny\nb\n",
+			want:         "a\n" + conflictBlock("x\n", "y\n") + "b\n",
+			wantConflict: true,
+		},
+		{
+			name:     "same insert on both sides",
+			base:     "a\nb\n",
+			current:  "a\nx\nb\n",
+			incoming: "a\nx\nb\nc\n",
+			want:     "a\nx\nb\nc\n",
+		},
+		{
+			name:     "delete next to an edit",
+			base:     "a\nb\nc\n",
+			current:  "a\nc\n",
+			incoming: "a\nb\nC\n",
+			want:     "a\nC\n",
+		},
+		{
+			name:     "missing trailing newline",
+			base:     "a\nb",
+			current:  "A\nb",
+			incoming: "a\nB",
+			want:     "A\nB",
+		},
+		{
+			name:         "conflict without trailing newline",
+			base:         "a\nb",
+			current:      "a\nB1",
+			incoming:     "a\nB2",
+			want:         "a\n" + conflictBlock("B1\n", "B2\n"),
+			wantConflict: true,
+		},
+		{
+			name:         "maxDiffCells fallback",
+			base:         bigBase,
+			current:      bigCurrent,
+			incoming:     bigIncoming,
+			want:         conflictBlock(bigCurrent, bigIncoming),
+			wantConflict: true,
+		},
+		{
+			name:     "below maxDiffCells the same edits merge",
+			base:     numberedLines(30, nil),
+			current:  numberedLines(30, map[int]string{0: "first", 29: "last"}),
+			incoming: numberedLines(30, map[int]string{15: "middle"}),
+			want:     numberedLines(30, map[int]string{0: "first", 15: "middle", 29: "last"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflict := merge3(tt.base, tt.current, tt.incoming)
+			if got != tt.want || conflict != tt.wantConflict {
+				t.Errorf("merge3() = %q, %v; want %q, %v", got, conflict, tt.want, tt.wantConflict)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+type NotebooksHandler struct {
+	DB *sql.DB
+}
+
+// HandleNotebooks serves /api/notebooks: every notebook the user owns or can
+// reach through a grant on it or on a parent, and creating new ones.
+func (h *NotebooksHandler) HandleNotebooks(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`
+			WITH RECURSIVE acc(id) AS (
+				SELECT b.id FROM notebooks b
+				WHERE b.owner_id = $1
+				   OR EXISTS (SELECT 1 FROM notebook_shares s WHERE s.notebook_id = b.id AND s.user_id = $1)
+				UNION
+				SELECT b.id FROM notebooks b JOIN acc ON b.parent_id = acc.id
+			)
+			SELECT b.id, b.owner_id, u.username, b.parent_id, b.name, b.created_at, b.updated_at
+			FROM notebooks b
+			JOIN users u ON u.id = b.owner_id
+			WHERE b.id IN (SELECT id FROM acc)
+			ORDER BY b.name, b.id`, userID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		notebooks := []models.Notebook{}
+		for rows.Next() {
+			var b models.Notebook
+			if err := rows.Scan(&b.ID, &b.OwnerID, &b.OwnerUsername, &b.ParentID, &b.Name, &b.CreatedAt, &b.UpdatedAt); err == nil {
+				notebooks = append(notebooks, b)
+			}
+		}
+		jsonResponse(w, notebooks, http.StatusOK)
+
+	case http.MethodPost:
+		var req models.NotebookReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			jsonError(w, "name required", http.StatusBadRequest)
+			return
+		}
+		if req.ParentID != nil && !requireNotebookRole(h.DB, w, *req.ParentID, userID, models.RoleEditor) {
+			return
+		}
+
+		var id int
+		err := h.DB.QueryRow(`INSERT INTO notebooks (owner_id, parent_id, name) VALUES ($1, $2, $3) RETURNING id`,
+			userID, req.ParentID, req.Name).Scan(&id)
+		if err != nil {
+			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.respondNotebook(w, id, models.RoleOwner, http.StatusCreated)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleNotebookByID serves /api/notebooks/{id} and its shares. Readers may
+// view it, editors rename it, and owners move, share or delete it. Deleting
+// a notebook deletes the notebooks inside it; their notes move back to the
+// top level.
+func (h *NotebooksHandler) HandleNotebookByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/api/notebooks/"):], "/"), "/")
+	notebookID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		jsonError(w, "invalid notebook id", http.StatusBadRequest)
+		return
+	}
+
+	role, err := notebookRole(h.DB, notebookID, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		jsonError(w, "notebook not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) > 1 {
+		if parts[1] == "shares" {
+			handleShares(h.DB, w, r, notebookShares, userID, notebookID, role, parts[2:])
+			return
+		}
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.respondNotebook(w, notebookID, role, http.StatusOK)
+
+	case http.MethodPut:
+		// PUT /notebooks/{id} → rename (editor) and move (owner)
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
+		var req models.NotebookReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			jsonError(w, "name required", http.StatusBadRequest)
+			return
+		}
+
+		var parentID *int
+		if err := h.DB.QueryRow(`SELECT parent_id FROM notebooks WHERE id=$1`, notebookID).Scan(&parentID); err != nil {
+			jsonError(w, "notebook not found", http.StatusNotFound)
+			return
+		}
+		if !sameParent(parentID, req.ParentID) {
+			if role != models.RoleOwner {
+				jsonError(w, "forbidden: only owner can move", http.StatusForbidden)
+				return
+			}
+			if req.ParentID != nil {
+				if !requireNotebookRole(h.DB, w, *req.ParentID, userID, models.RoleEditor) {
+					return
+				}
+				var cycle bool
+				err := h.DB.QueryRow(notebookChain("$1")+` SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)`,
+					*req.ParentID, notebookID).Scan(&cycle)
+				if err != nil {
+					jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if cycle {
+					jsonError(w, "cannot move a notebook into itself", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		// Moving takes away the grants of the old parents.
+		var readers noteReaders
+		if !sameParent(parentID, req.ParentID) {
+			readers = readersOf(h.DB, inNotebookSQL, notebookID)
+		}
+		_, err = h.DB.Exec(`UPDATE notebooks SET name=$1, parent_id=$2, updated_at=now() WHERE id=$3`,
+			req.Name, req.ParentID, notebookID)
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		publishLostAccess(readers)
+		h.respondNotebook(w, notebookID, role, http.StatusOK)
+
+	case http.MethodDelete:
+		if role != models.RoleOwner {
+			jsonError(w, "forbidden: only owner can delete", http.StatusForbidden)
+			return
+		}
+		// Its notes move to the top level, out of reach of its grants.
+		readers := readersOf(h.DB, inNotebookSQL, notebookID)
+		if _, err := h.DB.Exec(`DELETE FROM notebooks WHERE id=$1`, notebookID); err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		publishLostAccess(readers)
+		jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// requireNotebookRole checks that userID holds at least min on notebookID,
+// writing 404 or 403 and returning false otherwise.
+func requireNotebookRole(db *sql.DB, w http.ResponseWriter, notebookID, userID int, min string) bool {
+	role, err := notebookRole(db, notebookID, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if role == "" {
+		jsonError(w, "notebook not found", http.StatusNotFound)
+		return false
+	}
+	if !models.RoleAtLeast(role, min) {
+		jsonError(w, "forbidden: read-only access to notebook", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *NotebooksHandler) respondNotebook(w http.ResponseWriter, notebookID int, role string, status int) {
+	var b models.Notebook
+	err := h.DB.QueryRow(`
+		SELECT b.id, b.owner_id, u.username, b.parent_id, b.name, b.created_at, b.updated_at
+		FROM notebooks b JOIN users u ON u.id = b.owner_id
+		WHERE b.id = $1`, notebookID).
+		Scan(&b.ID, &b.OwnerID, &b.OwnerUsername, &b.ParentID, &b.Name, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		jsonError(w, "notebook not found", http.StatusNotFound)
+		return
+	}
+	b.Role = role
+	jsonResponse(w, b, status)
+}
+
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// moveNote serves PUT /api/notes/{id}/notebook. Only the note's owner may
+// file it, and only into a notebook they can edit; a null notebookId moves
+// it back to the top level.
+func (h *NotesHandler) moveNote(w http.ResponseWriter, r *http.Request, userID, noteID int, role string) {
+	if r.Method != http.MethodPut {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if role != models.RoleOwner {
+		jsonError(w, "forbidden: only owner can move", http.StatusForbidden)
+		return
+	}
+	var req models.MoveNoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.NotebookID != nil && !requireNotebookRole(h.DB, w, *req.NotebookID, userID, models.RoleEditor) {
+		return
+	}
+
+	readers := readersOf(h.DB, `n.id = $2`, noteID)
+	if _, err := h.DB.Exec(`UPDATE notes SET notebook_id=$1 WHERE id=$2`, req.NotebookID, noteID); err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publishNoteEvent(eventUpdated, noteID)
+	publishLostAccess(readers)
+	h.respondNote(w, userID, noteID, role, http.StatusOK)
+}
@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
 	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+	"github.com/lib/pq"
 )
 
 type NotesHandler struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Blobs storage.BlobStore
 }
 
 func (h *NotesHandler) HandleNotes(w http.ResponseWriter, r *http.Request) {
@@ -25,52 +29,45 @@ func (h *NotesHandler) HandleNotes(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		// GET /notes → Return ALL notes from all users
-		rows, err := h.DB.Query(`
-			SELECT n.id, n.owner_id, u.username, n.title, n.content, n.shared, n.favorite, n.updated_at
-			FROM notes n
-			JOIN users u ON u.id = n.owner_id
-			ORDER BY n.updated_at DESC`)
-		if err != nil {
-			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
+		h.listNotes(w, r, userID)
 
-		var notes []models.Note
-		for rows.Next() {
-			var n models.Note
-			if err := rows.Scan(&n.ID, &n.OwnerID, &n.OwnerUsername, &n.Title, &n.Content, &n.Shared, &n.Favorite, &n.Updated); err == nil {
-				notes = append(notes, n)
-			}
-		}
-		if len(notes) == 0 {
-			jsonResponse(w, []models.Note{}, http.StatusOK)
+	case http.MethodPost:
+		if t := r.URL.Query().Get("template"); t != "" {
+			h.createFromTemplate(w, r, userID, t)
 			return
 		}
-		jsonResponse(w, notes, http.StatusOK)
-
-	case http.MethodPost:
 		var req models.Note
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			jsonError(w, "invalid json", http.StatusBadRequest)
 			return
 		}
-		if req.Title == "" {
-			req.Title = "Untitled Note"
+		if err := prepareNote(&req); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.NotebookID != nil && !requireNotebookRole(h.DB, w, *req.NotebookID, userID, models.RoleEditor) {
+			return
 		}
 
-		var id int
-		q := `INSERT INTO notes (owner_id, title, content, shared, favorite, updated_at)
-			  VALUES ($1, $2, $3, $4, $5, now()) RETURNING id`
-		err := h.DB.QueryRow(q, userID, req.Title, req.Content, req.Shared, req.Favorite).Scan(&id)
+		tx, err := h.DB.Begin()
 		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		if err := insertNote(tx, userID, &req); err != nil {
+			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(); err != nil {
 			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		publishNoteEvent(eventCreated, req.ID)
 
-		req.ID = id
 		req.OwnerID = userID
+		req.Version = 1
 		req.Updated = time.Now()
 		_ = h.DB.QueryRow(`SELECT username FROM users WHERE id=$1`, userID).Scan(&req.OwnerUsername)
 		jsonResponse(w, req, http.StatusCreated)
@@ -80,6 +77,57 @@ func (h *NotesHandler) HandleNotes(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// prepareNote fills in defaults and normalizes the tags of a new note.
+func prepareNote(n *models.Note) error {
+	if n.Title == "" {
+		n.Title = "Untitled Note"
+	}
+	tags, err := normalizeTags(n.Tags)
+	if err != nil {
+		return err
+	}
+	n.Tags = tags
+	return nil
+}
+
+// insertNote creates a prepared note for userID with its first revision,
+// tags, wiki links, mentions and favorite flag, and sets n.ID. Every way of creating notes goes
+// through here.
+func insertNote(tx *sql.Tx, userID int, n *models.Note) error {
+	q := `INSERT INTO notes (owner_id, title, content, shared, notebook_id, updated_at)
+		  VALUES ($1, $2, $3, $4, $5, now()) RETURNING id`
+	if err := tx.QueryRow(q, userID, n.Title, n.Content, n.Shared, n.NotebookID).Scan(&n.ID); err != nil {
+		return err
+	}
+	if err := recordRevision(tx, n.ID, userID); err != nil {
+		return err
+	}
+	if err := setNoteTags(tx, n.ID, n.Tags); err != nil {
+		return err
+	}
+	if err := indexNoteContent(tx, n.ID, userID, n.Content); err != nil {
+		return err
+	}
+	if n.Favorite {
+		return setFavorite(tx, userID, n.ID, true)
+	}
+	return nil
+}
+
+// indexNoteContent refreshes what is derived from a note's content, its
+// wiki links and mentions. Call it in every transaction that changes the
+// content.
+func indexNoteContent(tx *sql.Tx, noteID, actorID int, content string) error {
+	if err := syncWikiLinks(tx, noteID, content); err != nil {
+		return err
+	}
+	return syncMentions(tx, noteID, actorID, content)
+}
+
+// HandleNoteByID serves a single note and its sub-resources. Notes the user
+// cannot read answer 404, so ids do not leak. Readers without the required
+// role get 403: viewers cannot edit, and only owners may change the shared
+// flag or move the note to the trash.
 func (h *NotesHandler) HandleNoteByID(w http.ResponseWriter, r *http.Request) {
 	userID, err := middlewares.GetUserIDFromCookie(r)
 	if err != nil {
@@ -87,63 +135,260 @@ func (h *NotesHandler) HandleNoteByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := r.URL.Path[len("/api/notes/"):]
-	noteID, err := strconv.Atoi(idStr)
+	// /api/notes/{id}[/{sub}/...]
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/api/notes/"):], "/"), "/")
+	noteID, err := strconv.Atoi(parts[0])
 	if err != nil {
 		jsonError(w, "invalid note id", http.StatusBadRequest)
 		return
 	}
 
+	// Trashed notes have no role, so restoring is checked on its own.
+	if len(parts) == 2 && parts[1] == "restore" {
+		h.restoreNote(w, r, userID, noteID)
+		return
+	}
+
+	role, err := noteRole(h.DB, noteID, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "shares":
+			handleShares(h.DB, w, r, noteShares, userID, noteID, role, parts[2:])
+		case "revisions":
+			h.handleRevisions(w, r, userID, noteID, role, parts[2:])
+		case "favorite":
+			h.handleFavorite(w, r, userID, noteID)
+		case "notebook":
+			h.moveNote(w, r, userID, noteID, role)
+		case "archive":
+			h.handleArchive(w, r, userID, noteID, role)
+		case "attachments":
+			h.handleAttachments(w, r, userID, noteID, role, parts[2:])
+		case "links":
+			h.handleLinks(w, r, userID, noteID, role, parts[2:])
+		case "backlinks":
+			h.handleBacklinks(w, r, userID, noteID)
+		case "comments":
+			h.handleComments(w, r, userID, noteID, role, parts[2:])
+		default:
+			jsonError(w, "not found", http.StatusNotFound)
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		// GET /notes/{id} → Any logged-in user can view any note
-		var n models.Note
-		q := `SELECT n.id, n.owner_id, u.username, n.title, n.content, n.shared, n.favorite, n.updated_at 
-		      FROM notes n JOIN users u ON u.id = n.owner_id WHERE n.id=$1`
-		err := h.DB.QueryRow(q, noteID).Scan(&n.ID, &n.OwnerID, &n.OwnerUsername, &n.Title, &n.Content, &n.Shared, &n.Favorite, &n.Updated)
+		// GET /notes/{id}?format=html → also the sanitized rendered content
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "html" {
+			jsonError(w, "invalid format", http.StatusBadRequest)
+			return
+		}
+		n, err := h.loadNote(userID, noteID, role)
 		if err != nil {
 			jsonError(w, "note not found", http.StatusNotFound)
 			return
 		}
+		if format == "html" {
+			if n.HTML, err = noteHTML(n.ID, n.Version, n.Content); err != nil {
+				jsonError(w, "render failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.Header().Set("ETag", versionETag(n.Version))
 		jsonResponse(w, n, http.StatusOK)
 
 	case http.MethodPut:
-		// PUT /notes/{id} → Any logged-in user can update any note
+		// PUT /notes/{id} → Editors and owners. Only owners change the shared
+		// flag. favorite is per user and ignored here; see /favorite. Tags
+		// are replaced only when the body has a tags field. With
+		// ?rewriteLinks=true a rename also rewrites [[Old Title]] in the
+		// other notes the user can edit.
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
 		var req models.Note
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			jsonError(w, "invalid json", http.StatusBadRequest)
 			return
 		}
+		if req.Tags != nil {
+			if req.Tags, err = normalizeTags(req.Tags); err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 
-		_, err = h.DB.Exec(`UPDATE notes 
-			SET title=$1, content=$2, shared=$3, favorite=$4, updated_at=now()
-			WHERE id=$5`, req.Title, req.Content, req.Shared, req.Favorite, noteID)
+		tx, err := h.DB.Begin()
 		if err != nil {
 			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		jsonResponse(w, map[string]string{"message": "updated"}, http.StatusOK)
+		defer tx.Rollback()
 
-	case http.MethodDelete:
-		// DELETE /notes/{id} → Only owner can delete
-		var ownerID int
-		err = h.DB.QueryRow(`SELECT owner_id FROM notes WHERE id=$1`, noteID).Scan(&ownerID)
+		var oldTitle, oldContent string
+		var oldShared bool
+		var oldTags []string
+		var current int
+		err = tx.QueryRow(`SELECT n.title, COALESCE(n.content, ''), n.shared, `+tagsSQL+`, n.version
+			FROM notes n WHERE n.id=$1 FOR UPDATE`, noteID).
+			Scan(&oldTitle, &oldContent, &oldShared, pq.Array(&oldTags), &current)
 		if err != nil {
 			jsonError(w, "note not found", http.StatusNotFound)
 			return
 		}
-		if ownerID != userID {
+		based, ok := clientVersion(r, req.Version, current)
+		if !ok {
+			jsonError(w, "precondition required: send If-Match or version", http.StatusPreconditionRequired)
+			return
+		}
+		// An owner may be unsharing the note; see who reads it now.
+		var readers noteReaders
+		if role == models.RoleOwner && oldShared && !req.Shared {
+			readers = readersOf(tx, `n.id = $2`, noteID)
+		}
+		merged := false
+		if based != current {
+			// Stale edit: merge it onto the current text when the changes
+			// do not overlap.
+			if !h.mergeStale(w, tx, userID, noteID, based, current, role, &req, oldTitle, oldContent) {
+				return
+			}
+			merged = true
+		}
+
+		// An autosave that resends the stored note keeps its version and
+		// publishes nothing.
+		tagsChanged := req.Tags != nil && !equalStrings(req.Tags, oldTags)
+		changed := req.Title != oldTitle || req.Content != oldContent ||
+			(role == models.RoleOwner && req.Shared != oldShared) || tagsChanged
+		version := current
+		if changed {
+			err = tx.QueryRow(`UPDATE notes 
+				SET title=$1, content=$2,
+				    shared = CASE WHEN $5 THEN $3 ELSE shared END,
+				    version=version+1, updated_at=now()
+				WHERE id=$4 RETURNING version`, req.Title, req.Content, req.Shared, noteID, role == models.RoleOwner).
+				Scan(&version)
+			if err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if tagsChanged {
+			if err := setNoteTags(tx, noteID, req.Tags); err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		// Only text changes make a new revision, or notify the owner; flag
+		// toggles do not.
+		if req.Title != oldTitle || req.Content != oldContent {
+			if err := recordRevision(tx, noteID, userID); err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err := notifyEdit(tx, noteID, userID); err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if req.Content != oldContent {
+			if err := indexNoteContent(tx, noteID, userID, req.Content); err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		var relinked []int
+		if req.Title != oldTitle && r.URL.Query().Get("rewriteLinks") == "true" {
+			if relinked, err = relinkRenamed(h.DB, tx, userID, noteID, oldTitle, req.Title); err != nil {
+				jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if changed {
+			publishNoteEvent(eventUpdated, noteID)
+			publishLostAccess(readers)
+		}
+		for _, id := range relinked {
+			publishNoteEvent(eventUpdated, id)
+		}
+		w.Header().Set("ETag", versionETag(version))
+		if merged {
+			jsonResponse(w, map[string]interface{}{
+				"message":  "merged",
+				"version":  version,
+				"title":    req.Title,
+				"content":  req.Content,
+				"relinked": len(relinked),
+			}, http.StatusOK)
+			return
+		}
+		jsonResponse(w, map[string]interface{}{"message": "updated", "version": version, "relinked": len(relinked)}, http.StatusOK)
+
+	case http.MethodPatch:
+		h.patchNote(w, r, userID, noteID, role)
+
+	case http.MethodDelete:
+		// DELETE /notes/{id} → Only owner can move it to the trash
+		if role != models.RoleOwner {
 			jsonError(w, "forbidden: only owner can delete", http.StatusForbidden)
 			return
 		}
-		_, err = h.DB.Exec(`DELETE FROM notes WHERE id=$1`, noteID)
+		var req models.Note
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				jsonError(w, "invalid json", http.StatusBadRequest)
+				return
+			}
+		}
+		var current int
+		if err := h.DB.QueryRow(`SELECT version FROM notes WHERE id=$1`, noteID).Scan(&current); err != nil {
+			jsonError(w, "note not found", http.StatusNotFound)
+			return
+		}
+		if !h.checkVersion(w, r, req.Version, current, userID, noteID, role) {
+			return
+		}
+		res, err := h.DB.Exec(`UPDATE notes SET deleted_at=now()
+			WHERE id=$1 AND version=$2 AND deleted_at IS NULL`, noteID, current)
 		if err != nil {
 			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+		if n, _ := res.RowsAffected(); n == 0 {
+			h.versionConflict(w, userID, noteID, role)
+			return
+		}
+		publishNoteEvent(eventDeleted, noteID)
+		jsonResponse(w, map[string]string{"message": "moved to trash"}, http.StatusOK)
 
 	default:
 		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
 }
+
+// loadNote fetches a note with its owner's username and whether userID
+// starred it. It does not check access; callers resolve role first.
+func (h *NotesHandler) loadNote(userID, noteID int, role string) (models.Note, error) {
+	var n models.Note
+	q := `SELECT n.id, n.owner_id, u.username, n.notebook_id, n.title, n.content, n.shared, ` + favoriteSQL + `, ` + tagsSQL + `, n.version, n.updated_at, n.archived_at
+	      FROM notes n JOIN users u ON u.id = n.owner_id WHERE n.id=$2`
+	err := h.DB.QueryRow(q, userID, noteID).Scan(&n.ID, &n.OwnerID, &n.OwnerUsername, &n.NotebookID, &n.Title, &n.Content, &n.Shared, &n.Favorite, pq.Array(&n.Tags), &n.Version, &n.Updated, &n.ArchivedAt)
+	n.Role = role
+	return n, err
+}
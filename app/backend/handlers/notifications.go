@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 100
+
+	// maxMentions caps the users one text can notify.
+	maxMentions = 50
+)
+
+// mentionPattern matches @username not preceded by a word character, so
+// e-mail addresses are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.\-]+)`)
+
+// sqlRunner is what *sql.DB and *sql.Tx have in common, so notifications
+// can be recorded inside the transaction that causes them.
+type sqlRunner interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// notificationVisibleSQL hides notifications about notes and notebooks the
+// user bound to $1 can no longer read. Notifications are aliased x, their
+// note n (LEFT JOIN) and their notebook b (LEFT JOIN).
+var notificationVisibleSQL = `(x.note_id IS NULL OR (n.id IS NOT NULL AND ` + noteVisibleSQL + `))
+	AND (x.notebook_id IS NULL OR EXISTS (` + notebookChain("x.notebook_id") + `
+		SELECT 1 FROM chain c
+		WHERE c.owner_id = $1
+		   OR EXISTS (SELECT 1 FROM notebook_shares bs WHERE bs.notebook_id = c.id AND bs.user_id = $1)))`
+
+const notificationFrom = `FROM notifications x
+	LEFT JOIN notes n ON n.id = x.note_id
+	LEFT JOIN notebooks b ON b.id = x.notebook_id
+	LEFT JOIN users a ON a.id = x.actor_id`
+
+type NotificationsHandler struct {
+	DB *sql.DB
+}
+
+// HandleNotifications serves GET /api/notifications: the user's inbox,
+// newest first. ?unread=true lists unread ones only; ?limit (default 50,
+// max 100) and ?before={id} page through older ones.
+func (h *NotificationsHandler) HandleNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	limit := defaultNotificationsLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxNotificationsLimit {
+			jsonError(w, "limit must be between 1 and "+strconv.Itoa(maxNotificationsLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	where := `x.user_id = $1 AND ` + notificationVisibleSQL
+	args := []interface{}{userID}
+	if params.Get("unread") == "true" {
+		where += ` AND x.read_at IS NULL`
+	}
+	if v := params.Get("before"); v != "" {
+		before, err := strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		args = append(args, before)
+		where += ` AND (x.created_at, x.id) < (SELECT created_at, id FROM notifications WHERE id = $2 AND user_id = $1)`
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT x.id, x.kind, x.actor_id, COALESCE(a.username, ''), x.note_id, COALESCE(n.title, ''),
+		       x.notebook_id, COALESCE(b.name, ''), x.comment_id, x.created_at, x.read_at
+		`+notificationFrom+`
+		WHERE `+where+`
+		ORDER BY x.created_at DESC, x.id DESC
+		LIMIT `+strconv.Itoa(limit), args...)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Notification{}
+	for rows.Next() {
+		var x models.Notification
+		err := rows.Scan(&x.ID, &x.Kind, &x.ActorID, &x.ActorUsername, &x.NoteID, &x.NoteTitle,
+			&x.NotebookID, &x.NotebookName, &x.CommentID, &x.CreatedAt, &x.ReadAt)
+		if err == nil {
+			x.Read = x.ReadAt != nil
+			list = append(list, x)
+		}
+	}
+	jsonResponse(w, list, http.StatusOK)
+}
+
+// HandleNotificationByID serves POST /api/notifications/{id}/read and
+// POST /api/notifications/read-all.
+func (h *NotificationsHandler) HandleNotificationByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/api/notifications/"):], "/"), "/")
+	if len(parts) == 1 && parts[0] == "read-all" {
+		res, err := h.DB.Exec(`UPDATE notifications SET read_at=now() WHERE user_id=$1 AND read_at IS NULL`, userID)
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		jsonResponse(w, map[string]interface{}{"message": "all read", "updated": n}, http.StatusOK)
+		return
+	}
+	if len(parts) != 2 || parts[1] != "read" {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	notificationID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		jsonError(w, "invalid notification id", http.StatusBadRequest)
+		return
+	}
+	res, err := h.DB.Exec(`UPDATE notifications SET read_at=COALESCE(read_at, now())
+		WHERE id=$1 AND user_id=$2`, notificationID, userID)
+	if err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		jsonError(w, "notification not found", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, map[string]string{"message": "read"}, http.StatusOK)
+}
+
+// HandleUnread serves GET /api/me/unread: unread notification counts for
+// the header, in total and per kind.
+func (h *NotificationsHandler) HandleUnread(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	counts, err := unreadCounts(h.DB, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, counts, http.StatusOK)
+}
+
+func unreadCounts(db *sql.DB, userID int) (models.UnreadCounts, error) {
+	var c models.UnreadCounts
+	rows, err := db.Query(`SELECT x.kind, COUNT(*) `+notificationFrom+`
+		WHERE x.user_id = $1 AND x.read_at IS NULL AND `+notificationVisibleSQL+`
+		GROUP BY x.kind`, userID)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var n int
+		if err := rows.Scan(&kind, &n); err != nil {
+			return c, err
+		}
+		switch kind {
+		case models.NotifyShare:
+			c.Share = n
+		case models.NotifyMention:
+			c.Mention = n
+		case models.NotifyEdit:
+			c.Edit = n
+		}
+		c.Total += n
+	}
+	return c, rows.Err()
+}
+
+// notify records a notification for userID unless the actor is the user.
+func notify(db sqlRunner, userID, actorID int, kind string, noteID, notebookID, commentID *int) error {
+	if userID == actorID {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO notifications (user_id, actor_id, kind, note_id, notebook_id, comment_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`, userID, actorID, kind, noteID, notebookID, commentID)
+	return err
+}
+
+// notifyEdit tells the owner that actorID edited their note. Repeated edits
+// by the same person bump the unread notification instead of adding one
+// per save.
+func notifyEdit(db sqlRunner, noteID, actorID int) error {
+	var ownerID int
+	if err := db.QueryRow(`SELECT owner_id FROM notes WHERE id=$1`, noteID).Scan(&ownerID); err != nil {
+		return err
+	}
+	if ownerID == actorID {
+		return nil
+	}
+	res, err := db.Exec(`UPDATE notifications SET created_at=now()
+		WHERE user_id=$1 AND actor_id=$2 AND kind='edit' AND note_id=$3 AND read_at IS NULL`, ownerID, actorID, noteID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	return notify(db, ownerID, actorID, models.NotifyEdit, &noteID, nil, nil)
+}
+
+// parseMentions returns the distinct lowercased usernames text mentions.
+// A trailing dot is taken as punctuation.
+func parseMentions(text string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(strings.TrimRight(m[1], "."))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+			if len(names) == maxMentions {
+				break
+			}
+		}
+	}
+	return names
+}
+
+// mentionedUsers resolves the users text mentions.
+func mentionedUsers(db sqlRunner, text string) ([]int, error) {
+	names := parseMentions(text)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	rows, err := db.Query(`SELECT id FROM users WHERE lower(username) = ANY($1) ORDER BY id`, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// notifyMentioned sends mention notifications to the users that can read
+// the note; telling anyone else would leak that it exists.
+func notifyMentioned(db sqlRunner, userIDs []int, actorID, noteID int, commentID *int) error {
+	for _, uid := range userIDs {
+		var visible bool
+		err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM notes n WHERE n.id = $2 AND `+noteVisibleSQL+`)`, uid, noteID).Scan(&visible)
+		if err != nil {
+			return err
+		}
+		if visible {
+			if err := notify(db, uid, actorID, models.NotifyMention, &noteID, nil, commentID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncMentions records who the note content mentions and notifies those
+// that were not mentioned before, so re-saving a note does not repeat
+// notifications. Only users who can read the note are recorded, so one who
+// is granted access later is notified by the next save that mentions them.
+func syncMentions(tx *sql.Tx, noteID, actorID int, content string) error {
+	ids, err := mentionedUsers(tx, content)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		_, err := tx.Exec(`DELETE FROM note_mentions WHERE note_id=$1`, noteID)
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM note_mentions WHERE note_id=$1 AND NOT (user_id = ANY($2))`, noteID, pq.Array(ids)); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`INSERT INTO note_mentions (note_id, user_id)
+		SELECT n.id, u.id FROM notes n, unnest($2::int[]) AS u(id)
+		WHERE n.id = $1 AND `+noteVisibleFor("u.id")+`
+		ON CONFLICT DO NOTHING RETURNING user_id`, noteID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	var added []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		added = append(added, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, uid := range added {
+		if err := notify(tx, uid, actorID, models.NotifyMention, &noteID, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyCommentMentions notifies users mentioned in a comment body who were
+// not already mentioned in its previous body.
+func notifyCommentMentions(db sqlRunner, noteID, commentID, actorID int, body, oldBody string) error {
+	ids, err := mentionedUsers(db, body)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	before, err := mentionedUsers(db, oldBody)
+	if err != nil {
+		return err
+	}
+	var added []int
+	for _, id := range ids {
+		if !slices.Contains(before, id) {
+			added = append(added, id)
+		}
+	}
+	return notifyMentioned(db, added, actorID, noteID, &commentID)
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+// patchOp is one operation of an RFC 6902 JSON Patch document.
+type patchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// patchableFields are the note members a PATCH may touch. version can only
+// be used in a JSON Patch "test" or as the merge patch's base version.
+var patchableFields = map[string]bool{"title": true, "content": true, "shared": true, "favorite": true, "tags": true}
+
+// patchNote serves PATCH /api/notes/{id}. It accepts a JSON Merge Patch
+// (RFC 7396, application/merge-patch+json or application/json) or a JSON
+// Patch (RFC 6902, application/json-patch+json) and only changes the fields
+// the patch touches. If-Match is honoured but not required: the row is
+// locked while the patch is applied, so fields nobody touched stay intact.
+// ?rewriteLinks=true works as for PUT. Viewers may patch only their own
+// favorite flag.
+func (h *NotesHandler) patchNote(w http.ResponseWriter, r *http.Request, userID, noteID int, role string) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var body []byte
+	if err := json.NewDecoder(r.Body).Decode((*json.RawMessage)(&body)); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var cur models.Note
+	err = tx.QueryRow(`SELECT n.title, COALESCE(n.content, ''), n.shared, `+favoriteSQL+`, `+tagsSQL+`, n.version
+		FROM notes n WHERE n.id=$2 FOR UPDATE`, userID, noteID).
+		Scan(&cur.Title, &cur.Content, &cur.Shared, &cur.Favorite, pq.Array(&cur.Tags), &cur.Version)
+	if err != nil {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+	doc := noteDocument(cur)
+
+	bodyVersion := 0
+	switch mediaType {
+	case "application/json-patch+json":
+		var ops []patchOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			jsonError(w, "json patch must be an array of operations", http.StatusBadRequest)
+			return
+		}
+		if err := applyJSONPatch(doc, ops); err != nil {
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, errPatchTestFailed) {
+				status = http.StatusConflict
+			}
+			jsonError(w, err.Error(), status)
+			return
+		}
+	case "application/merge-patch+json", "application/json", "":
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal(body, &patch); err != nil {
+			jsonError(w, "merge patch must be a json object", http.StatusBadRequest)
+			return
+		}
+		if v, ok := patch["version"]; ok {
+			if err := json.Unmarshal(v, &bodyVersion); err != nil {
+				jsonError(w, "version must be a number", http.StatusBadRequest)
+				return
+			}
+			delete(patch, "version")
+		}
+		if err := applyMergePatch(doc, patch); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		jsonError(w, "unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if _, _, ok := ifMatchVersions(r); ok || bodyVersion != 0 {
+		if !h.checkVersion(w, r, bodyVersion, cur.Version, userID, noteID, role) {
+			return
+		}
+	}
+
+	next, err := noteFromDocument(doc)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	tagsChanged := !equalStrings(next.Tags, cur.Tags)
+	changed := next.Title != cur.Title || next.Content != cur.Content || next.Shared != cur.Shared || tagsChanged
+	if changed && !models.RoleAtLeast(role, models.RoleEditor) {
+		jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+		return
+	}
+	if next.Shared != cur.Shared && role != models.RoleOwner {
+		jsonError(w, "forbidden: only owner can change sharing", http.StatusForbidden)
+		return
+	}
+	// favorite is per user and does not touch the note row or its version.
+	if next.Favorite != cur.Favorite {
+		if err := setFavorite(tx, userID, noteID, next.Favorite); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if tagsChanged {
+		if err := setNoteTags(tx, noteID, next.Tags); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	var readers noteReaders
+	if cur.Shared && !next.Shared {
+		readers = readersOf(tx, `n.id = $2`, noteID)
+	}
+	if changed {
+		_, err = tx.Exec(`UPDATE notes
+			SET title=$1, content=$2, shared=$3, version=version+1, updated_at=now()
+			WHERE id=$4`, next.Title, next.Content, next.Shared, noteID)
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if next.Title != cur.Title || next.Content != cur.Content {
+		if err := recordRevision(tx, noteID, userID); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := notifyEdit(tx, noteID, userID); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if next.Content != cur.Content {
+		if err := indexNoteContent(tx, noteID, userID, next.Content); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	var relinked []int
+	if next.Title != cur.Title && r.URL.Query().Get("rewriteLinks") == "true" {
+		if relinked, err = relinkRenamed(h.DB, tx, userID, noteID, cur.Title, next.Title); err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if changed {
+		publishNoteEvent(eventUpdated, noteID)
+		publishLostAccess(readers)
+	}
+	for _, id := range relinked {
+		publishNoteEvent(eventUpdated, id)
+	}
+	h.respondNote(w, userID, noteID, role, http.StatusOK)
+}
+
+// respondNote writes the stored note with its ETag.
+func (h *NotesHandler) respondNote(w http.ResponseWriter, userID, noteID int, role string, status int) {
+	n, err := h.loadNote(userID, noteID, role)
+	if err != nil {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("ETag", versionETag(n.Version))
+	jsonResponse(w, n, status)
+}
+
+// noteDocument is the JSON object patches are applied to.
+func noteDocument(n models.Note) map[string]json.RawMessage {
+	doc := map[string]json.RawMessage{}
+	for k, v := range map[string]interface{}{
+		"title":    n.Title,
+		"content":  n.Content,
+		"shared":   n.Shared,
+		"favorite": n.Favorite,
+		"tags":     n.Tags,
+		"version":  n.Version,
+	} {
+		doc[k], _ = json.Marshal(v)
+	}
+	return doc
+}
+
+// noteFromDocument validates a patched document and reads the patchable
+// fields back. A removed content or tags becomes empty; the other fields
+// cannot be removed.
+func noteFromDocument(doc map[string]json.RawMessage) (models.Note, error) {
+	var n models.Note
+	if err := decodeField(doc, "title", &n.Title); err != nil {
+		return n, err
+	}
+	if strings.TrimSpace(n.Title) == "" {
+		return n, errors.New("title must not be empty")
+	}
+	if _, ok := doc["content"]; ok {
+		if err := decodeField(doc, "content", &n.Content); err != nil {
+			return n, err
+		}
+	}
+	if err := decodeField(doc, "shared", &n.Shared); err != nil {
+		return n, err
+	}
+	if err := decodeField(doc, "favorite", &n.Favorite); err != nil {
+		return n, err
+	}
+	if _, ok := doc["tags"]; ok {
+		if err := decodeField(doc, "tags", &n.Tags); err != nil {
+			return n, err
+		}
+	}
+	tags, err := normalizeTags(n.Tags)
+	if err != nil {
+		return n, err
+	}
+	n.Tags = tags
+	return n, nil
+}
+
+func decodeField(doc map[string]json.RawMessage, name string, dst interface{}) error {
+	raw, ok := doc[name]
+	if !ok {
+		return errors.New(name + " cannot be removed")
+	}
+	if string(raw) == "null" {
+		return errors.New(name + " must not be null")
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return errors.New(name + " has the wrong type")
+	}
+	return nil
+}
+
+// applyMergePatch applies an RFC 7396 merge patch. Notes are flat, so every
+// member either replaces a field or, when null, removes it.
+func applyMergePatch(doc, patch map[string]json.RawMessage) error {
+	for name, value := range patch {
+		if !patchableFields[name] {
+			return errors.New("unknown or read-only field: " + name)
+		}
+		if string(value) == "null" {
+			delete(doc, name)
+			continue
+		}
+		doc[name] = value
+	}
+	return nil
+}
+
+var errPatchTestFailed = errors.New("json patch test failed")
+
+// applyJSONPatch applies RFC 6902 operations in order. The document is only
+// modified if every operation succeeds.
+func applyJSONPatch(doc map[string]json.RawMessage, ops []patchOp) error {
+	work := make(map[string]json.RawMessage, len(doc))
+	for k, v := range doc {
+		work[k] = v
+	}
+
+	for _, op := range ops {
+		name, err := patchField(op.Path)
+		if err != nil {
+			return err
+		}
+		if op.Op != "test" && !patchableFields[name] {
+			return errors.New("read-only field: " + name)
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return errors.New(op.Op + " requires a value")
+			}
+			if _, ok := work[name]; !ok && op.Op == "replace" {
+				return errors.New("replace on missing field: " + name)
+			}
+			work[name] = op.Value
+		case "remove":
+			if _, ok := work[name]; !ok {
+				return errors.New("remove on missing field: " + name)
+			}
+			delete(work, name)
+		case "test":
+			if op.Value == nil {
+				return errors.New("test requires a value")
+			}
+			if !jsonEqual(work[name], op.Value) {
+				return errPatchTestFailed
+			}
+		case "copy", "move":
+			from, err := patchField(op.From)
+			if err != nil {
+				return err
+			}
+			value, ok := work[from]
+			if !ok {
+				return errors.New(op.Op + " from missing field: " + from)
+			}
+			if op.Op == "move" {
+				if !patchableFields[from] {
+					return errors.New("read-only field: " + from)
+				}
+				delete(work, from)
+			}
+			work[name] = value
+		default:
+			return errors.New("unsupported op: " + op.Op)
+		}
+	}
+
+	for k := range doc {
+		delete(doc, k)
+	}
+	for k, v := range work {
+		doc[k] = v
+	}
+	return nil
+}
+
+// patchField maps a JSON Pointer to a top-level note field.
+func patchField(pointer string) (string, error) {
+	name := strings.TrimPrefix(pointer, "/")
+	if name == pointer || strings.Contains(name, "/") {
+		return "", errors.New("invalid path: " + pointer)
+	}
+	name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+	if !patchableFields[name] && name != "version" {
+		return "", errors.New("unknown field: " + name)
+	}
+	return name, nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+func testNoteDocument() map[string]json.RawMessage {
+	return noteDocument(models.Note{Title: "t", Content: "c", Tags: []string{"x"}, Version: 3})
+}
+
+func TestPatchField(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    string
+		wantErr string
+	}{
+		{pointer: "/title", want: "title"},
+		{pointer: "/version", want: "version"},
+		{pointer: "title", wantErr: "invalid path: title"},
+		{pointer: "/title/0", wantErr: "invalid path: /title/0"},
+		{pointer: "/nope", wantErr: "unknown field: nope"},
+		{pointer: "/a~1b", wantErr: "unknown field: a/b"},
+		{pointer: "/a~0b", wantErr: "unknown field: a~b"},
+		// ~0 is unescaped after ~1, so ~01 is a literal ~1
+		{pointer: "/~01", wantErr: "unknown field: ~1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := patchField(tt.pointer)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("patchField(%q) error = %v, want %q", tt.pointer, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("patchField(%q) = %q, %v; want %q", tt.pointer, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     string
+		wantErr string
+		want    map[string]string // fields to check; "" means removed
+	}{
+		{
+			name: "test then replace",
+			ops:  `[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/title","value":"new"}]`,
+			want: map[string]string{"title": `"new"`, "version": "3"},
+		},
+		{
+			name:    "test failure",
+			ops:     `[{"op":"test","path":"/title","value":"other"},{"op":"replace","path":"/title","value":"new"}]`,
+			wantErr: errPatchTestFailed.Error(),
+		},
+		{
+			name:    "later op failure rolls back",
+			ops:     `[{"op":"replace","path":"/title","value":"new"},{"op":"remove","path":"/nope"}]`,
+			wantErr: "unknown field: nope",
+		},
+		{
+			name:    "replace version",
+			ops:     `[{"op":"replace","path":"/version","value":4}]`,
+			wantErr: "read-only field: version",
+		},
+		{
+			name:    "move from a read-only field",
+			ops:     `[{"op":"move","from":"/version","path":"/content"}]`,
+			wantErr: "read-only field: version",
+		},
+		{
+			name: "move between fields",
+			ops:  `[{"op":"move","from":"/content","path":"/title"}]`,
+			want: map[string]string{"title": `"c"`, "content": ""},
+		},
+		{
+			name: "copy from version",
+			ops:  `[{"op":"copy","from":"/version","path":"/content"}]`,
+			want: map[string]string{"content": "3", "version": "3"},
+		},
+		{
+			name:    "escaped path",
+			ops:     `[{"op":"add","path":"/ti~1tle","value":"x"}]`,
+			wantErr: "unknown field: ti/tle",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []patchOp
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			doc := testNoteDocument()
+			err := applyJSONPatch(doc, ops)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("applyJSONPatch() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == errPatchTestFailed.Error() && !errors.Is(err, errPatchTestFailed) {
+					t.Errorf("error %v is not errPatchTestFailed", err)
+				}
+				// A failed patch leaves the document as it was.
+				for k, v := range testNoteDocument() {
+					if string(doc[k]) != string(v) {
+						t.Errorf("%s = %s after failed patch, want %s", k, doc[k], v)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyJSONPatch() error = %v", err)
+			}
+			checkDocument(t, doc, tt.want)
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+		want    models.Note
+	}{
+		{
+			name:  "replace title",
+			patch: `{"title":"new"}`,
+			want:  models.Note{Title: "new", Content: "c", Tags: []string{"x"}},
+		},
+		{
+			name:  "null removes content",
+			patch: `{"content":null}`,
+			want:  models.Note{Title: "t", Tags: []string{"x"}},
+		},
+		{
+			name:  "null removes tags",
+			patch: `{"tags":null}`,
+			want:  models.Note{Title: "t", Content: "c", Tags: []string{}},
+		},
+		{
+			name:    "version is rejected",
+			patch:   `{"version":4}`,
+			wantErr: "unknown or read-only field: version",
+		},
+		{
+			name:    "unknown field is rejected",
+			patch:   `{"owner":1}`,
+			wantErr: "unknown or read-only field: owner",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			doc := testNoteDocument()
+			err := applyMergePatch(doc, patch)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("applyMergePatch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyMergePatch() error = %v", err)
+			}
+			got, err := noteFromDocument(doc)
+			if err != nil {
+				t.Fatalf("noteFromDocument() error = %v", err)
+			}
+			if got.Title != tt.want.Title || got.Content != tt.want.Content || !equalStrings(got.Tags, tt.want.Tags) {
+				t.Errorf("patched note = %q, %q, %q; want %q, %q, %q",
+					got.Title, got.Content, got.Tags, tt.want.Title, tt.want.Content, tt.want.Tags)
+			}
+		})
+	}
+}
+
+func TestNoteFromDocumentRequiredFields(t *testing.T) {
+	for _, field := range []string{"title", "shared", "favorite"} {
+		doc := testNoteDocument()
+		delete(doc, field)
+		if _, err := noteFromDocument(doc); err == nil || !strings.Contains(err.Error(), "cannot be removed") {
+			t.Errorf("removing %s: error = %v, want cannot be removed", field, err)
+		}
+	}
+}
+
+// checkDocument compares the given fields of doc as JSON values; an empty
+// want means the field must be absent.
+func checkDocument(t *testing.T, doc map[string]json.RawMessage, want map[string]string) {
+	t.Helper()
+	for k, v := range want {
+		got, ok := doc[k]
+		if v == "" {
+			if ok {
+				t.Errorf("%s = %s, want it removed", k, got)
+			}
+			continue
+		}
+		if !jsonEqual(got, json.RawMessage(v)) {
+			t.Errorf("%s = %s, want %s", k, got, v)
+		}
+	}
+}
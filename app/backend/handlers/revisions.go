@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// recordRevision snapshots the note's current title, content and version as
+// its next revision. Call it inside the transaction that changed the note so
+// the note row lock keeps revision numbers in order.
+func recordRevision(tx *sql.Tx, noteID, editorID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO note_revisions (note_id, rev, title, content, editor_id, version)
+		SELECT n.id,
+		       COALESCE((SELECT MAX(rev) FROM note_revisions WHERE note_id = n.id), 0) + 1,
+		       n.title, n.content, $2, n.version
+		FROM notes n WHERE n.id = $1`, noteID, editorID)
+	return err
+}
+
+// revisionAtVersion returns the title and content the note had at version.
+// Text only changes together with a new revision, so that is the newest
+// revision saved at or before version. ok is false when history does not
+// reach back that far.
+func revisionAtVersion(tx *sql.Tx, noteID, version int) (title, content string, ok bool, err error) {
+	err = tx.QueryRow(`
+		SELECT title, COALESCE(content, '') FROM note_revisions
+		WHERE note_id = $1 AND version <= $2
+		ORDER BY version DESC LIMIT 1`, noteID, version).Scan(&title, &content)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	return title, content, err == nil, err
+}
+
+// handleRevisions serves /api/notes/{id}/revisions[/{rev}[/restore]].
+// Readers can browse history; restoring needs editor access.
+func (h *NotesHandler) handleRevisions(w http.ResponseWriter, r *http.Request, userID, noteID int, role string, rest []string) {
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.listRevisions(w, noteID)
+		return
+	}
+
+	rev, err := strconv.Atoi(rest[0])
+	if err != nil {
+		jsonError(w, "invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		var rv models.NoteRevision
+		err := h.DB.QueryRow(`
+			SELECT r.note_id, r.rev, r.title, COALESCE(r.content, ''), COALESCE(r.editor_id, 0), COALESCE(u.username, ''), r.created_at
+			FROM note_revisions r
+			LEFT JOIN users u ON u.id = r.editor_id
+			WHERE r.note_id = $1 AND r.rev = $2`, noteID, rev).
+			Scan(&rv.NoteID, &rv.Rev, &rv.Title, &rv.Content, &rv.EditorID, &rv.EditorUsername, &rv.CreatedAt)
+		if err != nil {
+			jsonError(w, "revision not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, rv, http.StatusOK)
+
+	case len(rest) == 2 && rest[1] == "restore":
+		// POST /notes/{id}/revisions/{rev}/restore → copy an old revision
+		// back onto the note, recorded as a new revision
+		if r.Method != http.MethodPost {
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+			return
+		}
+		h.restoreRevision(w, userID, noteID, rev)
+
+	default:
+		jsonError(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (h *NotesHandler) listRevisions(w http.ResponseWriter, noteID int) {
+	rows, err := h.DB.Query(`
+		SELECT r.note_id, r.rev, r.title, COALESCE(r.editor_id, 0), COALESCE(u.username, ''), r.created_at
+		FROM note_revisions r
+		LEFT JOIN users u ON u.id = r.editor_id
+		WHERE r.note_id = $1
+		ORDER BY r.rev DESC`, noteID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	revs := []models.NoteRevision{}
+	for rows.Next() {
+		var rv models.NoteRevision
+		if err := rows.Scan(&rv.NoteID, &rv.Rev, &rv.Title, &rv.EditorID, &rv.EditorUsername, &rv.CreatedAt); err == nil {
+			revs = append(revs, rv)
+		}
+	}
+	jsonResponse(w, revs, http.StatusOK)
+}
+
+func (h *NotesHandler) restoreRevision(w http.ResponseWriter, userID, noteID, rev int) {
+	tx, err := h.DB.Begin()
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE notes n SET title = r.title, content = r.content, version = n.version + 1, updated_at = now()
+		FROM note_revisions r
+		WHERE n.id = $1 AND r.note_id = n.id AND r.rev = $2`, noteID, rev)
+	if err != nil {
+		jsonError(w, "restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		jsonError(w, "revision not found", http.StatusNotFound)
+		return
+	}
+	if err := recordRevision(tx, noteID, userID); err != nil {
+		jsonError(w, "restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var content string
+	err = tx.QueryRow(`SELECT COALESCE(content, '') FROM notes WHERE id=$1`, noteID).Scan(&content)
+	if err == nil {
+		err = indexNoteContent(tx, noteID, userID, content)
+	}
+	if err == nil {
+		err = notifyEdit(tx, noteID, userID)
+	}
+	if err != nil {
+		jsonError(w, "restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonError(w, "restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publishNoteEvent(eventUpdated, noteID)
+	jsonResponse(w, map[string]string{"message": "restored"}, http.StatusOK)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// shareScope names the grant table of a shareable resource.
+type shareScope struct {
+	table    string // grant table, e.g. note_shares
+	key      string // column referencing the resource
+	resource string // resource table, which has an owner_id
+}
+
+var (
+	noteShares     = shareScope{table: "note_shares", key: "note_id", resource: "notes"}
+	notebookShares = shareScope{table: "notebook_shares", key: "notebook_id", resource: "notebooks"}
+)
+
+// setID fills the id field matching the scope.
+func (sc shareScope) setID(s *models.Share, id int) {
+	if sc == notebookShares {
+		s.NotebookID = id
+	} else {
+		s.NoteID = id
+	}
+}
+
+// handleShares serves /api/{notes,notebooks}/{id}/shares[/{userId}]. Anyone
+// who can read the resource may list its grants; only owners may grant,
+// change or revoke, except that grantees may always revoke their own access.
+func handleShares(db *sql.DB, w http.ResponseWriter, r *http.Request, sc shareScope, userID, id int, role string, rest []string) {
+	if len(rest) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			listShares(db, w, sc, id)
+		case http.MethodPost:
+			if role != models.RoleOwner {
+				jsonError(w, "forbidden: only owner can share", http.StatusForbidden)
+				return
+			}
+			grantShare(db, w, r, sc, userID, id)
+		default:
+			jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(rest) > 1 {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	targetID, err := strconv.Atoi(rest[0])
+	if err != nil {
+		jsonError(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		// PUT .../shares/{userId} → change role
+		if role != models.RoleOwner {
+			jsonError(w, "forbidden: only owner can change roles", http.StatusForbidden)
+			return
+		}
+		var req models.ShareReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if !models.ValidRole(req.Role) {
+			jsonError(w, "role must be viewer, editor or owner", http.StatusBadRequest)
+			return
+		}
+		res, err := db.Exec(`UPDATE `+sc.table+` SET role=$1 WHERE `+sc.key+`=$2 AND user_id=$3`, req.Role, id, targetID)
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			jsonError(w, "share not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, map[string]string{"message": "updated"}, http.StatusOK)
+
+	case http.MethodDelete:
+		// DELETE .../shares/{userId} → revoke (owner, or the grantee leaving)
+		if role != models.RoleOwner && targetID != userID {
+			jsonError(w, "forbidden: only owner can revoke", http.StatusForbidden)
+			return
+		}
+		// The grantee may still read the notes another way; only those
+		// who lose access are told.
+		where := `n.id = $2`
+		if sc == notebookShares {
+			where = inNotebookSQL
+		}
+		readers := readersOf(db, where, id)
+		res, err := db.Exec(`DELETE FROM `+sc.table+` WHERE `+sc.key+`=$1 AND user_id=$2`, id, targetID)
+		if err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			jsonError(w, "share not found", http.StatusNotFound)
+			return
+		}
+		publishLostAccess(readers)
+		jsonResponse(w, map[string]string{"message": "revoked"}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func listShares(db *sql.DB, w http.ResponseWriter, sc shareScope, id int) {
+	rows, err := db.Query(`
+		SELECT s.user_id, u.username, s.role, s.created_at
+		FROM `+sc.table+` s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.`+sc.key+` = $1
+		ORDER BY s.created_at`, id)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	shares := []models.Share{}
+	for rows.Next() {
+		var s models.Share
+		if err := rows.Scan(&s.UserID, &s.Username, &s.Role, &s.CreatedAt); err == nil {
+			sc.setID(&s, id)
+			shares = append(shares, s)
+		}
+	}
+	jsonResponse(w, shares, http.StatusOK)
+}
+
+// grantShare creates a grant for a username, or updates the role if the
+// user already has one, and notifies the grantee.
+func grantShare(db *sql.DB, w http.ResponseWriter, r *http.Request, sc shareScope, actorID, id int) {
+	var req models.ShareReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.Username == "" {
+		jsonError(w, "username required", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleViewer
+	}
+	if !models.ValidRole(req.Role) {
+		jsonError(w, "role must be viewer, editor or owner", http.StatusBadRequest)
+		return
+	}
+
+	s := models.Share{Username: req.Username, Role: req.Role}
+	sc.setID(&s, id)
+	var ownerID int
+	err := db.QueryRow(`SELECT owner_id FROM `+sc.resource+` WHERE id=$1`, id).Scan(&ownerID)
+	if err != nil {
+		jsonError(w, "not found", http.StatusNotFound)
+		return
+	}
+	err = db.QueryRow(`SELECT id FROM users WHERE username=$1`, req.Username).Scan(&s.UserID)
+	if err == sql.ErrNoRows {
+		jsonError(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if s.UserID == ownerID {
+		jsonError(w, "cannot share with the owner", http.StatusBadRequest)
+		return
+	}
+
+	err = db.QueryRow(`
+		INSERT INTO `+sc.table+` (`+sc.key+`, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (`+sc.key+`, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at`, id, s.UserID, s.Role).Scan(&s.CreatedAt)
+	if err != nil {
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sc == notebookShares {
+		err = notify(db, s.UserID, actorID, models.NotifyShare, nil, &id, nil)
+	} else {
+		publishNoteEvent(eventUpdated, id, s.UserID)
+		err = notify(db, s.UserID, actorID, models.NotifyShare, &id, nil, nil)
+	}
+	if err != nil {
+		log.Printf("notify share of %s %d: %v", sc.resource, id, err)
+	}
+	jsonResponse(w, s, http.StatusCreated)
+}
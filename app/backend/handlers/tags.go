@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+const (
+	maxTagsPerNote = 20
+	maxTagLength   = 50
+)
+
+// tagsSQL lists the tag names of note n as a text array, in the same byte
+// order normalizeTags uses.
+const tagsSQL = `COALESCE((SELECT array_agg(t.name ORDER BY t.name COLLATE "C")
+	FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+	WHERE nt.note_id = n.id), '{}')`
+
+type TagsHandler struct {
+	DB *sql.DB
+}
+
+// HandleTags serves GET /api/tags: every tag used on a note the user can
+// read, with the number of such notes, most used first.
+func (h *TagsHandler) HandleTags(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT t.name, COUNT(*)
+		FROM tags t
+		JOIN note_tags nt ON nt.tag_id = t.id
+		JOIN notes n ON n.id = nt.note_id
+		WHERE `+noteVisibleSQL+`
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name`, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.Count); err == nil {
+			tags = append(tags, t)
+		}
+	}
+	jsonResponse(w, tags, http.StatusOK)
+}
+
+// normalizeTags trims and lowercases tag names, drops duplicates and sorts
+// them, so stored and compared tag lists are canonical.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			return nil, errors.New("tag names must not be empty")
+		}
+		if utf8.RuneCountInString(t) > maxTagLength {
+			return nil, errors.New("tag names are limited to 50 characters")
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	if len(out) > maxTagsPerNote {
+		return nil, errors.New("a note can have at most 20 tags")
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// setNoteTags replaces the tags of a note, creating unknown tags on the way.
+// tags must already be normalized.
+func setNoteTags(tx *sql.Tx, noteID int, tags []string) error {
+	if _, err := tx.Exec(`INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`, pq.Array(tags)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM note_tags WHERE note_id=$1`, noteID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO note_tags (note_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`, noteID, pq.Array(tags))
+	return err
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+type TemplatesHandler struct {
+	DB *sql.DB
+}
+
+// templateVar matches {{name}} placeholders, spaces inside the braces allowed.
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// builtinTemplateVars are filled in by the server when a note is created.
+var builtinTemplateVars = map[string]bool{
+	"date": true, "time": true, "datetime": true, "weekday": true, "user": true,
+}
+
+const templateColumns = `id, owner_id, name, title, content, tags, created_at, updated_at`
+
+// HandleTemplates serves /api/templates: the user's templates and creating
+// new ones, either from scratch or from a note the user can read.
+func (h *TemplatesHandler) HandleTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`SELECT `+templateColumns+` FROM note_templates
+			WHERE owner_id=$1 ORDER BY lower(name), id`, userID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		templates := []models.Template{}
+		for rows.Next() {
+			if t, err := scanTemplate(rows); err == nil {
+				templates = append(templates, t)
+			}
+		}
+		jsonResponse(w, templates, http.StatusOK)
+
+	case http.MethodPost:
+		var req models.TemplateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if req.NoteID != nil {
+			// Save an existing note as a template
+			role, err := noteRole(h.DB, *req.NoteID, userID)
+			if err != nil {
+				jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if role == "" {
+				jsonError(w, "note not found", http.StatusNotFound)
+				return
+			}
+			err = h.DB.QueryRow(`SELECT n.title, COALESCE(n.content, ''), `+tagsSQL+` FROM notes n WHERE n.id=$1`, *req.NoteID).
+				Scan(&req.Title, &req.Content, pq.Array(&req.Tags))
+			if err != nil {
+				jsonError(w, "note not found", http.StatusNotFound)
+				return
+			}
+			if req.Name == "" {
+				req.Name = req.Title
+			}
+		}
+		if !validateTemplate(w, &req) {
+			return
+		}
+
+		t, err := scanTemplate(h.DB.QueryRow(`INSERT INTO note_templates (owner_id, name, title, content, tags)
+			VALUES ($1, $2, $3, $4, $5) RETURNING `+templateColumns,
+			userID, req.Name, req.Title, req.Content, pq.Array(req.Tags)))
+		if err != nil {
+			jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, t, http.StatusCreated)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleTemplateByID serves /api/templates/{id}. Templates are private, so
+// other users' templates answer 404.
+func (h *TemplatesHandler) HandleTemplateByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	templateID, err := strconv.Atoi(strings.Trim(r.URL.Path[len("/api/templates/"):], "/"))
+	if err != nil {
+		jsonError(w, "invalid template id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		t, err := loadTemplate(h.DB, templateID, userID)
+		if err != nil {
+			jsonError(w, "template not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, t, http.StatusOK)
+
+	case http.MethodPut:
+		var req models.TemplateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if !validateTemplate(w, &req) {
+			return
+		}
+		t, err := scanTemplate(h.DB.QueryRow(`UPDATE note_templates
+			SET name=$1, title=$2, content=$3, tags=$4, updated_at=now()
+			WHERE id=$5 AND owner_id=$6 RETURNING `+templateColumns,
+			req.Name, req.Title, req.Content, pq.Array(req.Tags), templateID, userID))
+		if err == sql.ErrNoRows {
+			jsonError(w, "template not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, t, http.StatusOK)
+
+	case http.MethodDelete:
+		res, err := h.DB.Exec(`DELETE FROM note_templates WHERE id=$1 AND owner_id=$2`, templateID, userID)
+		if err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			jsonError(w, "template not found", http.StatusNotFound)
+			return
+		}
+		jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createFromTemplate serves POST /api/notes?template=ID. Built-in variables
+// use the time in the requested timezone (UTC by default) and the user's
+// name; any variable, built-in or not, can be given in variables. Custom
+// variables without a value answer 400 listing what is missing.
+func (h *NotesHandler) createFromTemplate(w http.ResponseWriter, r *http.Request, userID int, templateParam string) {
+	templateID, err := strconv.Atoi(templateParam)
+	if err != nil {
+		jsonError(w, "invalid template id", http.StatusBadRequest)
+		return
+	}
+	var req models.FromTemplateReq
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	t, err := loadTemplate(h.DB, templateID, userID)
+	if err != nil {
+		jsonError(w, "template not found", http.StatusNotFound)
+		return
+	}
+	loc := time.UTC
+	if req.Timezone != "" {
+		if loc, err = time.LoadLocation(req.Timezone); err != nil {
+			jsonError(w, "invalid timezone", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.NotebookID != nil && !requireNotebookRole(h.DB, w, *req.NotebookID, userID, models.RoleEditor) {
+		return
+	}
+
+	var username string
+	if err := h.DB.QueryRow(`SELECT username FROM users WHERE id=$1`, userID).Scan(&username); err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().In(loc)
+	vars := map[string]string{
+		"date":     now.Format("2006-01-02"),
+		"time":     now.Format("15:04"),
+		"datetime": now.Format("2006-01-02 15:04"),
+		"weekday":  now.Weekday().String(),
+		"user":     username,
+	}
+	for k, v := range req.Variables {
+		vars[k] = v
+	}
+	var missing []string
+	for _, name := range t.Variables {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		jsonResponse(w, map[string]interface{}{
+			"error":   "missing template variables",
+			"missing": missing,
+		}, http.StatusBadRequest)
+		return
+	}
+
+	n := models.Note{
+		NotebookID: req.NotebookID,
+		Title:      expandTemplate(t.Title, vars),
+		Content:    expandTemplate(t.Content, vars),
+		Tags:       t.Tags,
+	}
+	if err := prepareNote(&n); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tx, err := h.DB.Begin()
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := insertNote(tx, userID, &n); err != nil {
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	publishNoteEvent(eventCreated, n.ID)
+	h.respondNote(w, userID, n.ID, models.RoleOwner, http.StatusCreated)
+}
+
+// validateTemplate trims and checks a template request, writing 400 and
+// returning false when it is invalid.
+func validateTemplate(w http.ResponseWriter, req *models.TemplateReq) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		jsonError(w, "name required", http.StatusBadRequest)
+		return false
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		req.Title = "Untitled Note"
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	req.Tags = tags
+	return true
+}
+
+// expandTemplate replaces every {{name}} that has a value; unknown ones are
+// left as written.
+func expandTemplate(s string, vars map[string]string) string {
+	return templateVar.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := vars[templateVar.FindStringSubmatch(m)[1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// customTemplateVars lists the non-built-in variables used in a template.
+func customTemplateVars(texts ...string) []string {
+	seen := map[string]bool{}
+	vars := []string{}
+	for _, s := range texts {
+		for _, m := range templateVar.FindAllStringSubmatch(s, -1) {
+			if name := m[1]; !builtinTemplateVars[name] && !seen[name] {
+				seen[name] = true
+				vars = append(vars, name)
+			}
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+func loadTemplate(db *sql.DB, templateID, userID int) (models.Template, error) {
+	return scanTemplate(db.QueryRow(`SELECT `+templateColumns+` FROM note_templates
+		WHERE id=$1 AND owner_id=$2`, templateID, userID))
+}
+
+func scanTemplate(row rowScanner) (models.Template, error) {
+	var t models.Template
+	err := row.Scan(&t.ID, &t.OwnerID, &t.Name, &t.Title, &t.Content, pq.Array(&t.Tags), &t.CreatedAt, &t.UpdatedAt)
+	t.Variables = customTemplateVars(t.Title, t.Content)
+	return t, err
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// thumbnailSizes are the bounding boxes, in pixels, thumbnails are made for.
+var thumbnailSizes = []int{128, 256, 512}
+
+const defaultThumbnailSize = 256
+
+// maxThumbnailSourcePixels guards against decompression bombs: a small file
+// can declare huge dimensions.
+const maxThumbnailSourcePixels = 50_000_000
+
+// thumbnailPollInterval is how often the worker looks for jobs it was not
+// woken for, e.g. ones queued by another instance.
+const thumbnailPollInterval = time.Minute
+
+// thumbnailLease is how long a claimed job may run before another worker
+// takes it over, assuming the one that claimed it died.
+const thumbnailLease = 10 * time.Minute
+
+// Thumbnail states of an attachment.
+const (
+	thumbNone    = "none"
+	thumbPending = "pending"
+	thumbWorking = "working"
+	thumbReady   = "ready"
+	thumbFailed  = "failed"
+)
+
+var thumbnailTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// initialThumbStatus queues thumbnails for images and skips other files.
+func initialThumbStatus(contentType string) string {
+	if thumbnailTypes[contentType] {
+		return thumbPending
+	}
+	return thumbNone
+}
+
+var thumbnailWake = make(chan struct{}, 1)
+
+// wakeThumbnailWorker tells the worker a job was queued, without blocking.
+func wakeThumbnailWorker() {
+	select {
+	case thumbnailWake <- struct{}{}:
+	default:
+	}
+}
+
+func thumbnailKey(key string, size int) string {
+	return key + "-thumb-" + strconv.Itoa(size) + ".jpg"
+}
+
+// deleteAttachmentBlobs removes an attachment's content and its thumbnails.
+func deleteAttachmentBlobs(ctx context.Context, blobs storage.BlobStore, key string) error {
+	for _, size := range thumbnailSizes {
+		if err := blobs.Delete(ctx, thumbnailKey(key, size)); err != nil {
+			return err
+		}
+	}
+	return blobs.Delete(ctx, key)
+}
+
+// StartThumbnailWorker generates thumbnails for queued image attachments in
+// the background. Jobs are claimed with SKIP LOCKED, so several backend
+// instances can share the queue; a claim that outlives thumbnailLease is
+// taken over, so jobs of a process that stopped are not lost.
+func StartThumbnailWorker(db *sql.DB, blobs storage.BlobStore) {
+	go func() {
+		for {
+			for {
+				done, err := processThumbnailJob(db, blobs)
+				if err != nil {
+					log.Printf("thumbnail queue: %v", err)
+					break
+				}
+				if !done {
+					break
+				}
+			}
+			select {
+			case <-thumbnailWake:
+			case <-time.After(thumbnailPollInterval):
+			}
+		}
+	}()
+}
+
+// processThumbnailJob handles one queued attachment. It returns false when
+// the queue is empty.
+func processThumbnailJob(db *sql.DB, blobs storage.BlobStore) (bool, error) {
+	var id int
+	var key string
+	err := db.QueryRow(`UPDATE attachments SET thumb_status='working', thumb_claimed_at=now()
+		WHERE id = (
+			SELECT id FROM attachments
+			WHERE (thumb_status='pending'
+			       OR (thumb_status='working' AND (thumb_claimed_at IS NULL
+			           OR thumb_claimed_at < now() - make_interval(secs => $1))))
+			  AND NOT pending AND note_id IS NOT NULL
+			ORDER BY id LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, storage_key`, thumbnailLease.Seconds()).Scan(&id, &key)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := thumbReady
+	if err := generateThumbnails(context.Background(), blobs, key); err != nil {
+		log.Printf("thumbnails for attachment %d: %v", id, err)
+		status = thumbFailed
+	}
+	_, err = db.Exec(`UPDATE attachments SET thumb_status=$1, thumb_claimed_at=NULL WHERE id=$2`, status, id)
+	return true, err
+}
+
+// generateThumbnails decodes the image under key and stores a JPEG for
+// every size in thumbnailSizes.
+func generateThumbnails(ctx context.Context, blobs storage.BlobStore, key string) error {
+	blob, err := blobs.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	cfg, _, err := image.DecodeConfig(blob)
+	if err != nil {
+		return err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return fmt.Errorf("image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err := blob.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(blob)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range thumbnailSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeToFit(img, size), &jpeg.Options{Quality: 80}); err != nil {
+			return err
+		}
+		if err := blobs.Put(ctx, thumbnailKey(key, size), &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resizeToFit scales src down to fit a size×size box, keeping its aspect
+// ratio, onto a white background since JPEG has no transparency. Smaller
+// images keep their size.
+func resizeToFit(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, xdraw.Over, nil)
+	return dst
+}
+
+// serveThumbnail serves GET /api/attachments/{id}/thumb?size=. It answers
+// 202 while the thumbnail is being generated and 404 for files that have
+// none.
+func (h *AttachmentsHandler) serveThumbnail(w http.ResponseWriter, r *http.Request, a models.Attachment, key string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	size := defaultThumbnailSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || !validThumbnailSize(n) {
+			jsonError(w, "size must be one of 128, 256 or 512", http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+
+	switch a.ThumbStatus {
+	case thumbReady:
+	case thumbPending, thumbWorking:
+		w.Header().Set("Retry-After", "2")
+		jsonResponse(w, map[string]string{"status": thumbPending}, http.StatusAccepted)
+		return
+	default:
+		jsonError(w, "no thumbnail for this attachment", http.StatusNotFound)
+		return
+	}
+
+	thumbKey := thumbnailKey(key, size)
+	if p, ok := h.Blobs.(storage.Presigner); ok {
+		u, err := p.PresignGet(r.Context(), thumbKey, "image/jpeg", "inline", presignExpiry)
+		if err != nil {
+			jsonError(w, "download failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-store")
+		http.Redirect(w, r, u, http.StatusFound)
+		return
+	}
+
+	blob, err := h.Blobs.Open(r.Context(), thumbKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		jsonError(w, "no thumbnail for this attachment", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "download failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+	http.ServeContent(w, r, "", a.CreatedAt, blob)
+}
+
+func validThumbnailSize(n int) bool {
+	for _, s := range thumbnailSizes {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
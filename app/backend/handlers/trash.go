@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+type TrashHandler struct {
+	DB *sql.DB
+}
+
+// HandleTrash serves /api/trash: the user's trashed notes, most recently
+// deleted first, and emptying the trash. Only owners can trash a note, so
+// the trash only ever holds the user's own notes.
+func (h *TrashHandler) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := h.DB.Query(`
+			SELECT n.id, n.owner_id, u.username, n.notebook_id, n.title,
+				left(regexp_replace(COALESCE(n.content, ''), '<[^>]*>', ' ', 'g'), 200),
+				n.shared, `+favoriteSQL+`, `+tagsSQL+`, n.version, n.updated_at, n.deleted_at
+			FROM notes n
+			JOIN users u ON u.id = n.owner_id
+			WHERE n.owner_id = $1 AND n.deleted_at IS NOT NULL
+			ORDER BY n.deleted_at DESC, n.id DESC`, userID)
+		if err != nil {
+			jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		notes := []models.NoteListItem{}
+		for rows.Next() {
+			var item models.NoteListItem
+			n := &item.Note
+			if err := rows.Scan(&n.ID, &n.OwnerID, &n.OwnerUsername, &n.NotebookID, &n.Title, &n.Excerpt,
+				&n.Shared, &n.Favorite, pq.Array(&n.Tags), &n.Version, &n.Updated, &n.DeletedAt); err == nil {
+				n.Role = models.RoleOwner
+				notes = append(notes, item)
+			}
+		}
+		jsonResponse(w, notes, http.StatusOK)
+
+	case http.MethodDelete:
+		// DELETE /trash → permanently delete everything in the trash
+		res, err := h.DB.Exec(`DELETE FROM notes WHERE owner_id=$1 AND deleted_at IS NOT NULL`, userID)
+		if err != nil {
+			jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		jsonResponse(w, map[string]interface{}{"message": "trash emptied", "deleted": n}, http.StatusOK)
+
+	default:
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleTrashByID serves DELETE /api/trash/{id}, which permanently deletes
+// one trashed note with its revisions, shares and tags.
+func (h *TrashHandler) HandleTrashByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	noteID, err := strconv.Atoi(strings.Trim(r.URL.Path[len("/api/trash/"):], "/"))
+	if err != nil {
+		jsonError(w, "invalid note id", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	res, err := h.DB.Exec(`DELETE FROM notes WHERE id=$1 AND owner_id=$2 AND deleted_at IS NOT NULL`, noteID, userID)
+	if err != nil {
+		jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		jsonError(w, "note not in trash", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, map[string]string{"message": "deleted"}, http.StatusOK)
+}
+
+// restoreNote serves POST /api/notes/{id}/restore, moving a trashed note
+// back to where it was. Only the owner can restore it; if its notebook was
+// deleted meanwhile it comes back at the top level.
+func (h *NotesHandler) restoreNote(w http.ResponseWriter, r *http.Request, userID, noteID int) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	res, err := h.DB.Exec(`UPDATE notes SET deleted_at=NULL
+		WHERE id=$1 AND owner_id=$2 AND deleted_at IS NOT NULL`, noteID, userID)
+	if err != nil {
+		jsonError(w, "restore failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		jsonError(w, "note not in trash", http.StatusNotFound)
+		return
+	}
+	publishNoteEvent(eventCreated, noteID)
+	h.respondNote(w, userID, noteID, models.RoleOwner, http.StatusOK)
+}
+
+// PurgeTrash permanently deletes notes that have been in the trash for
+// longer than retention and returns how many were removed.
+func PurgeTrash(db *sql.DB, retention time.Duration) (int64, error) {
+	res, err := db.Exec(`DELETE FROM notes
+		WHERE deleted_at IS NOT NULL AND deleted_at < now() - make_interval(secs => $1)`, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
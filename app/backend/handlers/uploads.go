@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+)
+
+// presignUpload serves POST /api/notes/{id}/attachments/presign. It records
+// a pending attachment and returns a presigned form the client POSTs the
+// file with, so large files bypass the backend. The form only accepts the
+// declared size and type. Only object stores support it.
+func (h *NotesHandler) presignUpload(w http.ResponseWriter, r *http.Request, userID, noteID int, role string) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !models.RoleAtLeast(role, models.RoleEditor) {
+		jsonError(w, "forbidden: read-only access", http.StatusForbidden)
+		return
+	}
+	p, ok := h.Blobs.(storage.Presigner)
+	if !ok {
+		jsonError(w, "direct uploads are not supported by this blob store", http.StatusNotImplemented)
+		return
+	}
+
+	var req models.PresignReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(req.ContentType)
+	if !allowedAttachmentTypes[contentType] {
+		jsonError(w, "file type not allowed: "+req.ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	if req.Size < 1 {
+		jsonError(w, "size required", http.StatusBadRequest)
+		return
+	}
+	if req.Size > maxAttachmentSize {
+		jsonError(w, "file too large: max "+strconv.Itoa(maxAttachmentSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	key, err := attachmentKey(noteID)
+	if err != nil {
+		jsonError(w, "upload failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	uploadURL, fields, err := p.PresignPost(r.Context(), key, contentType, req.Size, presignExpiry)
+	if err != nil {
+		jsonError(w, "upload failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a, _, err := scanAttachment(h.DB.QueryRow(`INSERT INTO attachments AS a
+			(note_id, uploader_id, filename, content_type, size, storage_key, pending)
+		VALUES ($1, $2, $3, $4, $5, $6, true)
+		RETURNING `+attachmentColumns, noteID, userID, cleanFilename(req.Filename), contentType, req.Size, key))
+	if err != nil {
+		jsonError(w, "db insert error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, models.PresignedUpload{Attachment: a, UploadURL: uploadURL, Method: http.MethodPost, Fields: fields}, http.StatusCreated)
+}
+
+// completeUpload serves POST /api/attachments/{id}/complete after a direct
+// upload. The stored object is checked against the same size and type
+// limits as a regular upload; objects that fail are deleted.
+func (h *AttachmentsHandler) completeUpload(w http.ResponseWriter, r *http.Request, userID, attachmentID int) {
+	if r.Method != http.MethodPost {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p, ok := h.Blobs.(storage.Presigner)
+	if !ok {
+		jsonError(w, "direct uploads are not supported by this blob store", http.StatusNotImplemented)
+		return
+	}
+
+	a, key, err := scanAttachment(h.DB.QueryRow(`SELECT `+attachmentColumns+` FROM attachments a
+		WHERE a.id=$1 AND a.note_id IS NOT NULL AND a.pending`, attachmentID))
+	if err != nil {
+		jsonError(w, "pending upload not found", http.StatusNotFound)
+		return
+	}
+	role, err := noteRole(h.DB, a.NoteID, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !models.RoleAtLeast(role, models.RoleEditor) {
+		jsonError(w, "pending upload not found", http.StatusNotFound)
+		return
+	}
+
+	size, err := p.Stat(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		jsonError(w, "file has not been uploaded", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		jsonError(w, "upload check failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if size > maxAttachmentSize {
+		h.rejectUpload(w, r, attachmentID, key, "file too large: max "+strconv.Itoa(maxAttachmentSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if size != a.Size {
+		h.rejectUpload(w, r, attachmentID, key, "file size does not match the declared size", http.StatusUnprocessableEntity)
+		return
+	}
+
+	blob, err := h.Blobs.Open(r.Context(), key)
+	if err != nil {
+		jsonError(w, "upload check failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(blob, head)
+	blob.Close()
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		jsonError(w, "upload check failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !allowedAttachmentTypes[contentType] {
+		h.rejectUpload(w, r, attachmentID, key, "file type not allowed: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	_, err = h.DB.Exec(`UPDATE attachments SET pending=false, size=$1, content_type=$2, thumb_status=$3 WHERE id=$4`,
+		size, contentType, initialThumbStatus(contentType), attachmentID)
+	if err != nil {
+		jsonError(w, "update failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wakeThumbnailWorker()
+	a, _, err = loadAttachment(h.DB, attachmentID)
+	if err != nil {
+		jsonError(w, "attachment not found", http.StatusNotFound)
+		return
+	}
+	jsonResponse(w, a, http.StatusOK)
+}
+
+// rejectUpload deletes a direct upload that failed its checks.
+func (h *AttachmentsHandler) rejectUpload(w http.ResponseWriter, r *http.Request, attachmentID int, key, message string, status int) {
+	if err := h.Blobs.Delete(r.Context(), key); err != nil {
+		jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.DB.Exec(`DELETE FROM attachments WHERE id=$1`, attachmentID); err != nil {
+		jsonError(w, "delete failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonError(w, message, status)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+)
+
+// versionETag formats a note version as a strong ETag.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersions parses the If-Match header into note versions. wildcard is
+// true for "If-Match: *". ok is false when the header is absent.
+func ifMatchVersions(r *http.Request) (versions []int, wildcard bool, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return nil, false, false
+	}
+	if header == "*" {
+		return nil, true, true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if v, err := strconv.Atoi(strings.Trim(tag, `"`)); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, false, true
+}
+
+// clientVersion resolves the version the client based its change on, taken
+// from If-Match or else from the request body. "If-Match: *" and lists that
+// contain current resolve to current; otherwise the newest listed version is
+// used. ok is false when the client sent neither.
+func clientVersion(r *http.Request, bodyVersion, current int) (version int, ok bool) {
+	versions, wildcard, ok := ifMatchVersions(r)
+	if !ok {
+		return bodyVersion, bodyVersion != 0
+	}
+	if wildcard {
+		return current, true
+	}
+	for _, v := range versions {
+		if v == current {
+			return current, true
+		}
+		if v > version {
+			version = v
+		}
+	}
+	return version, true
+}
+
+// checkVersion compares the client's version with the stored one. It writes
+// 428 when the client sent no version and 412 with the server copy on a
+// mismatch, returning false in both cases.
+func (h *NotesHandler) checkVersion(w http.ResponseWriter, r *http.Request, bodyVersion, current, userID, noteID int, role string) bool {
+	version, ok := clientVersion(r, bodyVersion, current)
+	if !ok {
+		jsonError(w, "precondition required: send If-Match or version", http.StatusPreconditionRequired)
+		return false
+	}
+	if version != current {
+		h.versionConflict(w, userID, noteID, role)
+		return false
+	}
+	return true
+}
+
+// versionConflict answers 412 Precondition Failed with the current server
+// copy of the note so the client can reconcile.
+func (h *NotesHandler) versionConflict(w http.ResponseWriter, userID, noteID int, role string) {
+	n, err := h.loadNote(userID, noteID, role)
+	if err != nil {
+		jsonError(w, "note not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("ETag", versionETag(n.Version))
+	jsonResponse(w, map[string]interface{}{
+		"error":   "version mismatch",
+		"current": n,
+	}, http.StatusPreconditionFailed)
+}
+
+// mergeStale three-way merges an edit based on an older version with the
+// current title and content, using the revision saved at that version as
+// the base. On success req holds the merged text. Without a base it answers
+// 412 like any version mismatch; when hunks overlap it answers 409 with the
+// conflict-marked text and both sides. It returns false if it wrote a
+// response.
+func (h *NotesHandler) mergeStale(w http.ResponseWriter, tx *sql.Tx, userID, noteID, based, current int, role string, req *models.Note, curTitle, curContent string) bool {
+	baseTitle, baseContent, ok, err := revisionAtVersion(tx, noteID, based)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok || based > current {
+		h.versionConflict(w, userID, noteID, role)
+		return false
+	}
+
+	title, titleConflict := merge3(baseTitle, curTitle, req.Title)
+	content, contentConflict := merge3(baseContent, curContent, req.Content)
+	if titleConflict || contentConflict {
+		n, err := h.loadNote(userID, noteID, role)
+		if err != nil {
+			jsonError(w, "note not found", http.StatusNotFound)
+			return false
+		}
+		w.Header().Set("ETag", versionETag(n.Version))
+		jsonResponse(w, map[string]interface{}{
+			"error":    "merge conflict",
+			"title":    title,
+			"content":  content,
+			"current":  n,
+			"incoming": map[string]string{"title": req.Title, "content": req.Content},
+		}, http.StatusConflict)
+		return false
+	}
+
+	req.Title, req.Content = title, content
+	return true
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
+	"github.com/lib/pq"
+)
+
+// maxWikiLinks caps the references stored per note.
+const maxWikiLinks = 500
+
+// wikiLinkPattern matches [[target]] and [[target|label]]. Migration 022
+// backfills with the same pattern, so keep them in step.
+var wikiLinkPattern = regexp.MustCompile(`\[\[([^\[\]|\n]+)(\|[^\[\]\n]*)?\]\]`)
+
+// wikiIDPattern is a [[#id]] target.
+var wikiIDPattern = regexp.MustCompile(`^#[0-9]{1,9}$`)
+
+// wikiTitleSQL is the key title references of note n are matched against.
+const wikiTitleSQL = `lower(btrim(n.title))`
+
+// wikiTitleKey is the Go side of wikiTitleSQL.
+func wikiTitleKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// parseWikiLinks returns the distinct note ids and title keys content
+// refers to.
+func parseWikiLinks(content string) (ids []int, titles []string) {
+	seenID := map[int]bool{}
+	seenTitle := map[string]bool{}
+	for _, m := range wikiLinkPattern.FindAllStringSubmatch(content, -1) {
+		if len(ids)+len(titles) >= maxWikiLinks {
+			break
+		}
+		target := strings.TrimSpace(m[1])
+		if wikiIDPattern.MatchString(target) {
+			id, _ := strconv.Atoi(target[1:])
+			if !seenID[id] {
+				seenID[id] = true
+				ids = append(ids, id)
+			}
+			continue
+		}
+		if key := wikiTitleKey(target); key != "" && !seenTitle[key] {
+			seenTitle[key] = true
+			titles = append(titles, key)
+		}
+	}
+	return ids, titles
+}
+
+// syncWikiLinks replaces the stored references of noteID with those in
+// content. Ids of notes that do not exist are dropped.
+func syncWikiLinks(tx *sql.Tx, noteID int, content string) error {
+	if _, err := tx.Exec(`DELETE FROM note_wikilinks WHERE source_id=$1`, noteID); err != nil {
+		return err
+	}
+	ids, titles := parseWikiLinks(content)
+	if len(ids) > 0 {
+		_, err := tx.Exec(`INSERT INTO note_wikilinks (source_id, target_id)
+			SELECT $1, n.id FROM notes n WHERE n.id = ANY($2)`, noteID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+	}
+	if len(titles) > 0 {
+		_, err := tx.Exec(`INSERT INTO note_wikilinks (source_id, target_title)
+			SELECT $1, unnest($2::text[])`, noteID, pq.Array(titles))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteWikiLinks points [[oldTitle]] references at newTitle in content,
+// keeping any label. ok is false when nothing changed.
+func rewriteWikiLinks(content, oldTitle, newTitle string) (string, bool) {
+	oldKey := wikiTitleKey(oldTitle)
+	changed := false
+	out := wikiLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		m := wikiLinkPattern.FindStringSubmatch(link)
+		if wikiTitleKey(m[1]) != oldKey {
+			return link
+		}
+		changed = true
+		return "[[" + newTitle + m[2] + "]]"
+	})
+	return out, changed
+}
+
+// relinkRenamed rewrites title references to a renamed note in every other
+// note the user can edit, recording a revision for each, and returns the
+// notes that changed. Notes the user cannot edit keep the old title and the
+// link stays unresolved until someone edits it. New titles that cannot be
+// written inside [[...]] are left alone.
+func relinkRenamed(db *sql.DB, tx *sql.Tx, userID, noteID int, oldTitle, newTitle string) ([]int, error) {
+	newTitle = strings.TrimSpace(newTitle)
+	if wikiTitleKey(oldTitle) == wikiTitleKey(newTitle) || newTitle == "" ||
+		strings.ContainsAny(newTitle, "[]|\n") || wikiIDPattern.MatchString(newTitle) {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(`SELECT source_id FROM note_wikilinks
+		WHERE target_title=$1 AND source_id <> $2 ORDER BY source_id`, wikiTitleKey(oldTitle), noteID)
+	if err != nil {
+		return nil, err
+	}
+	var sources []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sources = append(sources, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var relinked []int
+	for _, sourceID := range sources {
+		role, err := noteRole(db, sourceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !models.RoleAtLeast(role, models.RoleEditor) {
+			continue
+		}
+		var content string
+		err = tx.QueryRow(`SELECT COALESCE(content, '') FROM notes WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, sourceID).Scan(&content)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, ok := rewriteWikiLinks(content, oldTitle, newTitle)
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE notes SET content=$1, version=version+1, updated_at=now() WHERE id=$2`, content, sourceID); err != nil {
+			return nil, err
+		}
+		if err := recordRevision(tx, sourceID, userID); err != nil {
+			return nil, err
+		}
+		if err := indexNoteContent(tx, sourceID, userID, content); err != nil {
+			return nil, err
+		}
+		if err := notifyEdit(tx, sourceID, userID); err != nil {
+			return nil, err
+		}
+		relinked = append(relinked, sourceID)
+	}
+	return relinked, nil
+}
+
+// handleBacklinks serves GET /api/notes/{id}/backlinks: the notes the user
+// can read that link here, by id or by the note's current title.
+func (h *NotesHandler) handleBacklinks(w http.ResponseWriter, r *http.Request, userID, noteID int) {
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rows, err := h.DB.Query(`
+		SELECT n.id, n.title, u.username, n.updated_at
+		FROM notes n JOIN users u ON u.id = n.owner_id
+		WHERE n.id <> $2 AND `+noteVisibleSQL+`
+		  AND EXISTS (
+			SELECT 1 FROM note_wikilinks l, notes t
+			WHERE t.id = $2 AND l.source_id = n.id
+			  AND (l.target_id = t.id OR l.target_title = lower(btrim(t.title))))
+		ORDER BY n.updated_at DESC, n.id DESC`, userID, noteID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	refs := []models.NoteRef{}
+	for rows.Next() {
+		var ref models.NoteRef
+		if err := rows.Scan(&ref.ID, &ref.Title, &ref.OwnerUsername, &ref.Updated); err == nil {
+			refs = append(refs, ref)
+		}
+	}
+	jsonResponse(w, refs, http.StatusOK)
+}
+
+// HandleGraph serves GET /api/notes/graph: every note the user can read
+// that is not archived, and the links between them. A title reference
+// shared by several notes links to each of them.
+func (h *NotesHandler) HandleGraph(w http.ResponseWriter, r *http.Request) {
+	userID, err := middlewares.GetUserIDFromCookie(r)
+	if err != nil {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		jsonError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Both queries read the same snapshot, so edges never name a missing node.
+	tx, err := h.DB.BeginTx(r.Context(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	visible := `SELECT n.id, n.title, ` + wikiTitleSQL + ` AS key, u.username, n.updated_at
+		FROM notes n JOIN users u ON u.id = n.owner_id
+		WHERE n.archived_at IS NULL AND ` + noteVisibleSQL
+
+	graph := models.NoteGraph{Nodes: []models.NoteRef{}, Edges: []models.GraphEdge{}}
+	rows, err := tx.Query(visible+` ORDER BY n.id`, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var ref models.NoteRef
+		var key string
+		if err := rows.Scan(&ref.ID, &ref.Title, &key, &ref.OwnerUsername, &ref.Updated); err == nil {
+			graph.Nodes = append(graph.Nodes, ref)
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`WITH v AS (`+visible+`)
+		SELECT DISTINCT l.source_id, t.id
+		FROM note_wikilinks l
+		JOIN v s ON s.id = l.source_id
+		JOIN v t ON t.id = l.target_id OR t.key = l.target_title
+		WHERE t.id <> l.source_id
+		ORDER BY l.source_id, t.id`, userID)
+	if err != nil {
+		jsonError(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.GraphEdge
+		if err := rows.Scan(&e.Source, &e.Target); err == nil {
+			graph.Edges = append(graph.Edges, e)
+		}
+	}
+	jsonResponse(w, graph, http.StatusOK)
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	// Template timezones are resolved with time.LoadLocation; the alpine
+	// image ships no zoneinfo, so embed it.
+	_ "time/tzdata"
 
 	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/handlers"
 	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/middlewares"
@@ -19,9 +22,33 @@ func main() {
 	// Set database for logging middleware
 	middlewares.SetLogDB(db)
 
+	// Attachment storage
+	blobs := newBlobStore()
+
+	// Purge notes that outlived the trash retention, and their attachments
+	startPurge(db, blobs, trashRetention())
+
+	// Generate thumbnails for image attachments
+	handlers.StartThumbnailWorker(db, blobs)
+
+	// Deliver note changes to /api/events streams
+	handlers.StartNoteEvents(db)
+
+	// Imports do not survive a restart
+	handlers.FailInterruptedImports(db)
+
 	// Initialize handlers
 	authHandler := &handlers.AuthHandler{DB: db}
-	notesHandler := &handlers.NotesHandler{DB: db}
+	notesHandler := &handlers.NotesHandler{DB: db, Blobs: blobs}
+	tagsHandler := &handlers.TagsHandler{DB: db}
+	notebooksHandler := &handlers.NotebooksHandler{DB: db}
+	trashHandler := &handlers.TrashHandler{DB: db}
+	attachmentsHandler := &handlers.AttachmentsHandler{DB: db, Blobs: blobs}
+	publicHandler := &handlers.PublicHandler{DB: db}
+	exportHandler := &handlers.ExportHandler{DB: db}
+	importHandler := &handlers.ImportHandler{DB: db}
+	templatesHandler := &handlers.TemplatesHandler{DB: db}
+	notificationsHandler := &handlers.NotificationsHandler{DB: db}
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -30,11 +57,33 @@ func main() {
 	mux.Handle("/api/register", middlewares.Logging(http.HandlerFunc(authHandler.HandleRegister)))
 	mux.Handle("/api/login", middlewares.Logging(http.HandlerFunc(authHandler.HandleLogin)))
 	mux.Handle("/api/me", middlewares.Logging(http.HandlerFunc(authHandler.HandleMe)))
+	mux.Handle("/api/me/unread", middlewares.Logging(http.HandlerFunc(notificationsHandler.HandleUnread)))
 	mux.Handle("/api/logout", middlewares.Logging(http.HandlerFunc(authHandler.HandleLogout)))
 
 	// Notes routes
 	mux.Handle("/api/notes", middlewares.Logging(http.HandlerFunc(notesHandler.HandleNotes)))
 	mux.Handle("/api/notes/", middlewares.Logging(http.HandlerFunc(notesHandler.HandleNoteByID)))
+	mux.Handle("/api/notes/archive", middlewares.Logging(http.HandlerFunc(notesHandler.HandleBulkArchive)))
+	mux.Handle("/api/notes/graph", middlewares.Logging(http.HandlerFunc(notesHandler.HandleGraph)))
+	mux.Handle("/api/tags", middlewares.Logging(http.HandlerFunc(tagsHandler.HandleTags)))
+	mux.Handle("/api/trash", middlewares.Logging(http.HandlerFunc(trashHandler.HandleTrash)))
+	mux.Handle("/api/trash/", middlewares.Logging(http.HandlerFunc(trashHandler.HandleTrashByID)))
+	mux.Handle("/api/attachments/", middlewares.Logging(http.HandlerFunc(attachmentsHandler.HandleAttachmentByID)))
+	mux.Handle("/api/export", middlewares.Logging(http.HandlerFunc(exportHandler.HandleExport)))
+	mux.Handle("/api/import", middlewares.Logging(http.HandlerFunc(importHandler.HandleImport)))
+	mux.Handle("/api/import/", middlewares.Logging(http.HandlerFunc(importHandler.HandleImportByID)))
+	mux.Handle("/api/templates", middlewares.Logging(http.HandlerFunc(templatesHandler.HandleTemplates)))
+	mux.Handle("/api/templates/", middlewares.Logging(http.HandlerFunc(templatesHandler.HandleTemplateByID)))
+	mux.Handle("/api/notifications", middlewares.Logging(http.HandlerFunc(notificationsHandler.HandleNotifications)))
+	mux.Handle("/api/notifications/", middlewares.Logging(http.HandlerFunc(notificationsHandler.HandleNotificationByID)))
+	mux.Handle("/api/events", middlewares.Logging(http.HandlerFunc(notesHandler.HandleEvents)))
+
+	// Public share links (no login required)
+	mux.Handle("/api/public/", middlewares.Logging(http.HandlerFunc(publicHandler.HandlePublic)))
+
+	// Notebook routes
+	mux.Handle("/api/notebooks", middlewares.Logging(http.HandlerFunc(notebooksHandler.HandleNotebooks)))
+	mux.Handle("/api/notebooks/", middlewares.Logging(http.HandlerFunc(notebooksHandler.HandleNotebookByID)))
 
 	addr := ":" + serverPort
 	log.Printf("backend listening on %s", addr)
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/models"
 )
@@ -21,15 +24,39 @@ func SetLogDB(db *sql.DB) {
 	LogDB = db
 }
 
+// redactedFields are JSON keys whose values are never logged: passwords,
+// share link tokens and the URLs that carry them, and presigned upload URLs
+// and the signed policy fields that go with them
+var redactedFields = map[string]bool{
+	"password":  true,
+	"token":     true,
+	"url":       true,
+	"uploadUrl": true,
+	"fields":    true,
+}
+
+// maxLoggedBody is the most of a request body saved to the log
+const maxLoggedBody = 64 << 10
+
+// publicLinkPrefix is the path of share links, whose last segment is the token
+const publicLinkPrefix = "/api/public/"
+
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Capture request body
+		// Capture up to maxLoggedBody of JSON and text request bodies; the
+		// handler still reads the whole body, so its own size limit applies.
+		// Uploads and other binary bodies are streamed to it untouched
 		var requestBody []byte
-		if r.Body != nil {
-			requestBody, _ = io.ReadAll(r.Body)
-			r.Body = io.NopCloser(bytes.NewBuffer(requestBody)) // Restore body for handlers
+		truncated := false
+		if r.Body != nil && loggableBody(r.Header.Get("Content-Type")) {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, maxLoggedBody+1))
+			truncated = len(requestBody) > maxLoggedBody
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body} // Restore body for handlers
 		}
 
 		// Capture request headers (mask sensitive data)
@@ -42,8 +69,8 @@ func Logging(next http.Handler) http.Handler {
 				} else {
 					headers[name] = "***MASKED***"
 				}
-			} else if strings.ToLower(name) == "cookie" {
-				// Mask cookie values
+			} else if strings.ToLower(name) == "cookie" || strings.ToLower(name) == "x-link-password" {
+				// Mask cookie values and share link passwords
 				headers[name] = "***MASKED***"
 			} else {
 				headers[name] = strings.Join(values, ", ")
@@ -71,17 +98,23 @@ func Logging(next http.Handler) http.Handler {
 		duration := time.Since(start)
 		durationMs := int(duration.Milliseconds())
 
+		// Share link tokens are credentials; keep them out of every log
+		endpoint := r.URL.Path
+		if strings.HasPrefix(endpoint, publicLinkPrefix) {
+			endpoint = publicLinkPrefix + "***MASKED***"
+		}
+
 		// Log to console
-		log.Printf("%s %s %d %s", r.Method, r.URL.Path, lrw.statusCode, duration)
+		log.Printf("%s %s %d %s", r.Method, endpoint, lrw.statusCode, duration)
 
 		// Save to database asynchronously
 		if LogDB != nil {
 			go saveLogToDB(
 				r.Method,
-				r.URL.Path,
+				endpoint,
 				string(headersJSON),
-				string(requestBody),
-				lrw.body.String(),
+				logPayload(requestBody, truncated),
+				redactBody(lrw.body.Bytes()),
 				lrw.statusCode,
 				durationMs,
 				userID,
@@ -102,11 +135,98 @@ func (l *logResponseWriter) WriteHeader(code int) {
 }
 
 func (l *logResponseWriter) Write(b []byte) (int, error) {
-	// Capture response body
-	l.body.Write(b)
+	// Capture response body, skipping file downloads and event streams,
+	// which would grow the buffer for as long as the client stays connected.
+	// Redirect bodies only repeat the Location, which may be a presigned URL
+	if l.statusCode >= 300 && l.statusCode < 400 {
+		return l.ResponseWriter.Write(b)
+	}
+	if ct := l.Header().Get("Content-Type"); ct == "" || strings.HasPrefix(ct, "application/json") ||
+		(strings.HasPrefix(ct, "text/") && !strings.HasPrefix(ct, "text/event-stream")) {
+		l.body.Write(b)
+	}
 	return l.ResponseWriter.Write(b)
 }
 
+// Flush sends buffered data to the client, so streaming handlers work
+// behind the logger
+func (l *logResponseWriter) Flush() {
+	_ = l.FlushError()
+}
+
+// FlushError is Flush for http.ResponseController, reporting writers that
+// cannot flush
+func (l *logResponseWriter) FlushError() error {
+	return http.NewResponseController(l.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// clear the write deadline of a long-lived stream
+func (l *logResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
+// loggableBody tells whether a request body of this content type is saved
+// to the log: JSON and text only, as binary bodies cannot be stored as text
+func loggableBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		strings.HasPrefix(mediaType, "text/")
+}
+
+// logPayload is the logged form of a captured request body. A truncated body
+// cannot be redacted, so only its size is logged
+func logPayload(body []byte, truncated bool) string {
+	if truncated {
+		return fmt.Sprintf("***TRUNCATED: over %d bytes***", maxLoggedBody)
+	}
+	if !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0 {
+		return "***BINARY***"
+	}
+	return redactBody(body)
+}
+
+// redactBody masks redactedFields anywhere in a JSON body. Bodies that are
+// not JSON are returned as they are
+func redactBody(body []byte) string {
+	var v interface{}
+	if len(body) == 0 || json.Unmarshal(body, &v) != nil {
+		return string(body)
+	}
+	if !redactValue(v) {
+		return string(body)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// redactValue masks redactedFields in v in place and reports whether it
+// found any
+func redactValue(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if redactedFields[k] {
+				v[k] = "***MASKED***"
+				found = true
+			} else if redactValue(field) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactValue(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
 func saveLogToDB(method, endpoint, headers, payload, responseBody string, status, durationMs int, userID sql.NullInt64) {
 	if LogDB == nil {
 		return
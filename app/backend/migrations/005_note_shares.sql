@@ -0,0 +1,11 @@
+-- per-user sharing grants
+CREATE TABLE IF NOT EXISTS note_shares (
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+  created_at TIMESTAMP DEFAULT now(),
+  PRIMARY KEY (note_id, user_id)
+);
+
+-- index for "shared with me" lookups
+CREATE INDEX IF NOT EXISTS idx_note_shares_user ON note_shares(user_id);
@@ -0,0 +1,16 @@
+-- saved versions of each note, numbered per note
+CREATE TABLE IF NOT EXISTS note_revisions (
+  id SERIAL PRIMARY KEY,
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  rev INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  content TEXT,
+  editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT now(),
+  UNIQUE (note_id, rev)
+);
+
+-- existing notes start with their current text as revision 1
+INSERT INTO note_revisions (note_id, rev, title, content, editor_id, created_at)
+SELECT id, 1, title, content, owner_id, updated_at FROM notes
+ON CONFLICT (note_id, rev) DO NOTHING;
@@ -0,0 +1,2 @@
+-- version counter for optimistic concurrency (ETag / If-Match)
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
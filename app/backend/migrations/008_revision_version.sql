@@ -0,0 +1,11 @@
+-- note version each revision was saved as, used as the merge base
+ALTER TABLE note_revisions ADD COLUMN IF NOT EXISTS version INTEGER;
+
+-- the latest revision holds the note's current text
+UPDATE note_revisions r SET version = n.version
+FROM notes n
+WHERE r.note_id = n.id
+  AND r.version IS NULL
+  AND r.rev = (SELECT MAX(rev) FROM note_revisions WHERE note_id = n.id);
+
+CREATE INDEX IF NOT EXISTS idx_note_revisions_version ON note_revisions(note_id, version);
@@ -0,0 +1,9 @@
+-- full-text search over title (weight A) and content (weight B).
+-- content holds editor HTML, so tags are stripped before indexing.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS search tsvector
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('simple', regexp_replace(COALESCE(content, ''), '<[^>]*>', ' ', 'g')), 'B')
+  ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_notes_search ON notes USING GIN (search);
@@ -0,0 +1,2 @@
+-- keyset pagination of the notes listing on (updated_at, id)
+CREATE INDEX IF NOT EXISTS idx_notes_updated ON notes(updated_at DESC, id DESC);
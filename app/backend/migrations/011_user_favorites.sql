@@ -0,0 +1,16 @@
+-- favorites are per user instead of a flag on the note
+CREATE TABLE IF NOT EXISTS user_favorites (
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT now(),
+  PRIMARY KEY (user_id, note_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_favorites_note ON user_favorites(note_id);
+
+-- the old global flag could be set by anyone; keep it for the owner
+INSERT INTO user_favorites (user_id, note_id)
+SELECT owner_id, id FROM notes WHERE favorite = true
+ON CONFLICT (user_id, note_id) DO NOTHING;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS favorite;
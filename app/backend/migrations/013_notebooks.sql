@@ -0,0 +1,27 @@
+-- nested notebooks (folders) owned by users
+CREATE TABLE IF NOT EXISTS notebooks (
+  id SERIAL PRIMARY KEY,
+  owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  parent_id INTEGER REFERENCES notebooks(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notebooks_owner ON notebooks(owner_id);
+CREATE INDEX IF NOT EXISTS idx_notebooks_parent ON notebooks(parent_id);
+
+-- grants on a notebook cascade to everything inside it
+CREATE TABLE IF NOT EXISTS notebook_shares (
+  notebook_id INTEGER NOT NULL REFERENCES notebooks(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+  created_at TIMESTAMP DEFAULT now(),
+  PRIMARY KEY (notebook_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notebook_shares_user ON notebook_shares(user_id);
+
+-- deleting a notebook moves its notes back to the top level
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS notebook_id INTEGER REFERENCES notebooks(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_notebook ON notes(notebook_id);
@@ -0,0 +1,3 @@
+-- deleted notes stay in the trash until restored or purged
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_notes_trash ON notes(owner_id, deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- archived notes leave the default listing but stay readable and searchable
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_notes_archived ON notes(owner_id, archived_at) WHERE archived_at IS NOT NULL;
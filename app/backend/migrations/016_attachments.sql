@@ -0,0 +1,15 @@
+-- files attached to notes; the bytes live in the blob store under storage_key
+CREATE TABLE IF NOT EXISTS attachments (
+  id SERIAL PRIMARY KEY,
+  -- set to NULL when the note is deleted; the cleanup job then removes the blob
+  note_id INTEGER REFERENCES notes(id) ON DELETE SET NULL,
+  uploader_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  filename TEXT NOT NULL,
+  content_type TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  storage_key TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_note ON attachments(note_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphan ON attachments(id) WHERE note_id IS NULL;
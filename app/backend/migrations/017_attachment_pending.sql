@@ -0,0 +1,3 @@
+-- attachments uploaded straight to the object store stay pending until the
+-- backend has checked the uploaded object
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
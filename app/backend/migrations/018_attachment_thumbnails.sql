@@ -0,0 +1,5 @@
+-- thumbnails of image attachments are generated in the background:
+-- none (not an image), pending, working, ready or failed
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumb_status VARCHAR(10) NOT NULL DEFAULT 'none'
+  CHECK (thumb_status IN ('none', 'pending', 'working', 'ready', 'failed'));
+CREATE INDEX IF NOT EXISTS idx_attachments_thumb_queue ON attachments(id) WHERE thumb_status = 'pending';
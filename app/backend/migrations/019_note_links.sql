@@ -0,0 +1,15 @@
+-- public read-only links to a note; only a SHA-256 of the token is stored
+CREATE TABLE IF NOT EXISTS note_links (
+  id SERIAL PRIMARY KEY,
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  password_hash TEXT,
+  expires_at TIMESTAMP,
+  max_views INTEGER CHECK (max_views > 0),
+  views INTEGER NOT NULL DEFAULT 0,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_links_note ON note_links(note_id);
@@ -0,0 +1,29 @@
+-- [[Title]] and [[#id]] references between notes, rebuilt whenever a note's
+-- content is saved. Title references are stored lowercased and resolved when
+-- read, so they follow renames and start working once the note exists.
+CREATE TABLE IF NOT EXISTS note_wikilinks (
+  source_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  target_id INTEGER REFERENCES notes(id) ON DELETE CASCADE,
+  target_title TEXT,
+  CHECK ((target_id IS NULL) <> (target_title IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_wikilinks_id ON note_wikilinks(source_id, target_id) WHERE target_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_wikilinks_title ON note_wikilinks(source_id, target_title) WHERE target_title IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_note_wikilinks_target_id ON note_wikilinks(target_id);
+CREATE INDEX IF NOT EXISTS idx_note_wikilinks_target_title ON note_wikilinks(target_title);
+CREATE INDEX IF NOT EXISTS idx_notes_title_key ON notes(lower(btrim(title)));
+
+-- backfill from existing notes, with the same pattern the server parses
+WITH refs AS (
+  SELECT DISTINCT n.id AS source_id, btrim(m[1]) AS ref
+  FROM notes n
+  CROSS JOIN LATERAL regexp_matches(COALESCE(n.content, ''), '\[\[([^][|\n]+)(\|[^][\n]*)?\]\]', 'g') AS m
+)
+INSERT INTO note_wikilinks (source_id, target_id, target_title)
+SELECT r.source_id, NULL, lower(r.ref) FROM refs r
+WHERE r.ref <> '' AND r.ref !~ '^#[0-9]{1,9}$'
+UNION
+SELECT r.source_id, t.id, NULL FROM refs r
+JOIN notes t ON t.id = CASE WHEN r.ref ~ '^#[0-9]{1,9}$' THEN substr(r.ref, 2)::int END
+ON CONFLICT DO NOTHING;
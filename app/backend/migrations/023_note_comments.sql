@@ -0,0 +1,23 @@
+-- threaded comments on notes; top-level comments may quote a range of the
+-- content. Comments with replies are blanked instead of deleted so the
+-- thread stays readable.
+CREATE TABLE IF NOT EXISTS note_comments (
+  id SERIAL PRIMARY KEY,
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  parent_id INTEGER REFERENCES note_comments(id) ON DELETE CASCADE,
+  author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  body TEXT NOT NULL,
+  anchor_start INTEGER,
+  anchor_end INTEGER,
+  anchor_text TEXT,
+  resolved_at TIMESTAMP,
+  resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT now(),
+  updated_at TIMESTAMP DEFAULT now(),
+  deleted_at TIMESTAMP,
+  CHECK ((anchor_start IS NULL) = (anchor_end IS NULL)),
+  CHECK (anchor_start IS NULL OR (anchor_start >= 0 AND anchor_end > anchor_start))
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_comments_note ON note_comments(note_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_note_comments_parent ON note_comments(parent_id);
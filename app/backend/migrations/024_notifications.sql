@@ -0,0 +1,22 @@
+-- in-app notifications: shares, @mentions and edits to notes you own
+CREATE TABLE IF NOT EXISTS notifications (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  kind VARCHAR(10) NOT NULL CHECK (kind IN ('share', 'mention', 'edit')),
+  note_id INTEGER REFERENCES notes(id) ON DELETE CASCADE,
+  notebook_id INTEGER REFERENCES notebooks(id) ON DELETE CASCADE,
+  comment_id INTEGER REFERENCES note_comments(id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT now(),
+  read_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- users mentioned in a note's current content, so each mention notifies once
+CREATE TABLE IF NOT EXISTS note_mentions (
+  note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  PRIMARY KEY (note_id, user_id)
+);
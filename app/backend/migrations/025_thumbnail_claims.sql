@@ -0,0 +1,5 @@
+-- when a worker claimed a thumbnail job; claims older than the lease are
+-- taken to belong to a worker that died and are picked up again, as are
+-- jobs claimed before this column existed
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumb_claimed_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_attachments_thumb_claims ON attachments(thumb_claimed_at) WHERE thumb_status = 'working';
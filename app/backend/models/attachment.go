@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	NoteID      int       `json:"noteId"`
+	UploaderID  *int      `json:"uploaderId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	ThumbStatus string    `json:"thumbStatus"`
+	ThumbURL    string    `json:"thumbUrl,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// PresignReq describes a file the client wants to upload straight to the
+// object store.
+type PresignReq struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// PresignedUpload tells the client where to POST the file, as a multipart
+// form with Fields followed by a "file" field; it then calls
+// POST /api/attachments/{id}/complete.
+type PresignedUpload struct {
+	Attachment Attachment        `json:"attachment"`
+	UploadURL  string            `json:"uploadUrl"`
+	Method     string            `json:"method"`
+	Fields     map[string]string `json:"fields"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Comment is a comment on a note with its replies. Deleted comments that
+// still have replies keep their place in the thread with an empty body.
+type Comment struct {
+	ID             int            `json:"id"`
+	NoteID         int            `json:"noteId"`
+	ParentID       *int           `json:"parentId"`
+	AuthorID       *int           `json:"authorId"`
+	AuthorUsername string         `json:"authorUsername"`
+	Body           string         `json:"body"`
+	Anchor         *CommentAnchor `json:"anchor,omitempty"`
+	Resolved       bool           `json:"resolved"`
+	ResolvedAt     *time.Time     `json:"resolvedAt,omitempty"`
+	ResolvedBy     string         `json:"resolvedBy,omitempty"`
+	Deleted        bool           `json:"deleted"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	Replies        []Comment      `json:"replies"`
+}
+
+// CommentAnchor is a range of the note content in UTF-16 code units, the
+// way JavaScript indexes strings. Text is the quoted range as it was when
+// the comment was made; Detached means it no longer occurs in the note.
+type CommentAnchor struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Text     string `json:"text"`
+	Detached bool   `json:"detached,omitempty"`
+}
+
+// CommentReq is the body for creating or editing a comment. ParentID and
+// Anchor are only read on creation.
+type CommentReq struct {
+	Body     string         `json:"body"`
+	ParentID *int           `json:"parentId"`
+	Anchor   *CommentAnchor `json:"anchor"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ImportJob reports the progress of an import. Errors lists the items that
+// could not be imported.
+type ImportJob struct {
+	ID         int           `json:"id"`
+	Format     string        `json:"format"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Imported   int           `json:"imported"`
+	Failed     int           `json:"failed"`
+	Errors     []ImportError `json:"errors"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	FinishedAt *time.Time    `json:"finishedAt"`
+}
+
+type ImportError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ShareLink is a public read-only link to a note. Token is only returned
+// when the link is created.
+type ShareLink struct {
+	ID          int        `json:"id"`
+	NoteID      int        `json:"noteId"`
+	CreatedBy   *int       `json:"createdBy"`
+	HasPassword bool       `json:"hasPassword"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	MaxViews    *int       `json:"maxViews"`
+	Views       int        `json:"views"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Token       string     `json:"token,omitempty"`
+	URL         string     `json:"url,omitempty"`
+}
+
+type LinkReq struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Password  string     `json:"password"`
+	MaxViews  *int       `json:"maxViews"`
+}
+
+// PublicNote is what an anonymous visitor of a share link sees.
+type PublicNote struct {
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	HTML          string    `json:"html"`
+	Tags          []string  `json:"tags"`
+	OwnerUsername string    `json:"ownerUsername"`
+	Updated       time.Time `json:"updatedAt"`
+}
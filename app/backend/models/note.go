@@ -3,12 +3,54 @@ package models
 import "time"
 
 type Note struct {
+	ID            int        `json:"id"`
+	OwnerID       int        `json:"ownerId"`
+	OwnerUsername string     `json:"ownerUsername,omitempty"`
+	NotebookID    *int       `json:"notebookId"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	HTML          string     `json:"html,omitempty"`
+	Excerpt       string     `json:"excerpt,omitempty"`
+	Shared        bool       `json:"shared"`
+	Favorite      bool       `json:"favorite"`
+	Tags          []string   `json:"tags"`
+	Version       int        `json:"version"`
+	Updated       time.Time  `json:"updatedAt"`
+	ArchivedAt    *time.Time `json:"archivedAt,omitempty"`
+	DeletedAt     *time.Time `json:"deletedAt,omitempty"`
+	Role          string     `json:"role,omitempty"`
+	Snippet       string     `json:"snippet,omitempty"`
+	Rank          float64    `json:"rank,omitempty"`
+}
+
+// NoteListItem is a note in a listing, where content is left out unless it
+// was asked for.
+type NoteListItem struct {
+	Note
+	Content string `json:"content,omitempty"`
+}
+
+// ArchiveReq is the body of POST /api/notes/archive.
+type ArchiveReq struct {
+	OlderThanDays int `json:"olderThanDays"`
+}
+
+// NoteRef is a note as it appears in backlinks and the link graph.
+type NoteRef struct {
 	ID            int       `json:"id"`
-	OwnerID       int       `json:"ownerId"`
-	OwnerUsername string    `json:"ownerUsername,omitempty"`
 	Title         string    `json:"title"`
-	Content       string    `json:"content"`
-	Shared        bool      `json:"shared"`
-	Favorite      bool      `json:"favorite"`
+	OwnerUsername string    `json:"ownerUsername"`
 	Updated       time.Time `json:"updatedAt"`
 }
+
+// NoteGraph is the response of GET /api/notes/graph; edges point from the
+// linking note to the linked one.
+type NoteGraph struct {
+	Nodes []NoteRef   `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphEdge struct {
+	Source int `json:"source"`
+	Target int `json:"target"`
+}
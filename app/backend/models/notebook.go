@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Notebook struct {
+	ID            int       `json:"id"`
+	OwnerID       int       `json:"ownerId"`
+	OwnerUsername string    `json:"ownerUsername,omitempty"`
+	ParentID      *int      `json:"parentId"`
+	Name          string    `json:"name"`
+	Role          string    `json:"role,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type NotebookReq struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parentId"`
+}
+
+type MoveNoteReq struct {
+	NotebookID *int `json:"notebookId"`
+}
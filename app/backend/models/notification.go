@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Notification kinds.
+const (
+	NotifyShare   = "share"
+	NotifyMention = "mention"
+	NotifyEdit    = "edit"
+)
+
+// Notification tells a user that someone shared something with them,
+// mentioned them in a note or comment, or edited a note they own.
+type Notification struct {
+	ID            int        `json:"id"`
+	Kind          string     `json:"kind"`
+	ActorID       *int       `json:"actorId"`
+	ActorUsername string     `json:"actorUsername"`
+	NoteID        *int       `json:"noteId,omitempty"`
+	NoteTitle     string     `json:"noteTitle,omitempty"`
+	NotebookID    *int       `json:"notebookId,omitempty"`
+	NotebookName  string     `json:"notebookName,omitempty"`
+	CommentID     *int       `json:"commentId,omitempty"`
+	Read          bool       `json:"read"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ReadAt        *time.Time `json:"readAt,omitempty"`
+}
+
+// UnreadCounts is the response of GET /api/me/unread.
+type UnreadCounts struct {
+	Total   int `json:"total"`
+	Share   int `json:"share"`
+	Mention int `json:"mention"`
+	Edit    int `json:"edit"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type NoteRevision struct {
+	NoteID         int       `json:"noteId"`
+	Rev            int       `json:"rev"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content,omitempty"`
+	EditorID       int       `json:"editorId,omitempty"`
+	EditorUsername string    `json:"editorUsername,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Access roles on a note, from weakest to strongest.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+type Share struct {
+	NoteID     int       `json:"noteId,omitempty"`
+	NotebookID int       `json:"notebookId,omitempty"`
+	UserID     int       `json:"userId"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ShareReq struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// ValidRole reports whether role can be granted through a share.
+func ValidRole(role string) bool {
+	return role == RoleViewer || role == RoleEditor || role == RoleOwner
+}
+
+// RoleAtLeast reports whether role grants at least the access of min.
+// An empty role means no access at all.
+func RoleAtLeast(role, min string) bool {
+	return roleRank(role) >= roleRank(min)
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	}
+	return 0
+}
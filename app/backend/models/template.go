@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Template is a note skeleton. Variables lists the custom {{placeholders}}
+// a client has to prompt for; built-in ones like {{date}} are filled in by
+// the server.
+type Template struct {
+	ID        int       `json:"id"`
+	OwnerID   int       `json:"ownerId"`
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	Variables []string  `json:"variables"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TemplateReq creates or updates a template. With NoteID set, title,
+// content and tags are copied from that note.
+type TemplateReq struct {
+	Name    string   `json:"name"`
+	Title   string   `json:"title"`
+	Content string   `json:"content"`
+	Tags    []string `json:"tags"`
+	NoteID  *int     `json:"noteId"`
+}
+
+// FromTemplateReq is the body of POST /api/notes?template=ID.
+type FromTemplateReq struct {
+	Variables  map[string]string `json:"variables"`
+	Timezone   string            `json:"timezone"`
+	NotebookID *int              `json:"notebookId"`
+}
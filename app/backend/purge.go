@@ -0,0 +1,49 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/handlers"
+	"github.com/Arga-12/SimpleNotesSharingApp/app/backend/storage"
+)
+
+// purgeInterval is how often expired trash and orphaned attachments are
+// cleaned up.
+const purgeInterval = time.Hour
+
+// trashRetention reads TRASH_RETENTION_DAYS (default 30). Zero keeps
+// trashed notes until they are deleted by hand.
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(getenvLocal("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || days < 0 {
+		log.Printf("invalid TRASH_RETENTION_DAYS, using 30")
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// startPurge removes expired notes from the trash and the blobs of
+// attachments whose note is gone, now and then every purgeInterval, in the
+// background.
+func startPurge(db *sql.DB, blobs storage.BlobStore, retention time.Duration) {
+	go func() {
+		for {
+			if retention > 0 {
+				if n, err := handlers.PurgeTrash(db, retention); err != nil {
+					log.Printf("purge trash: %v", err)
+				} else if n > 0 {
+					log.Printf("purged %d notes from trash", n)
+				}
+			}
+			if n, err := handlers.PurgeOrphanAttachments(db, blobs); err != nil {
+				log.Printf("purge attachments: %v", err)
+			} else if n > 0 {
+				log.Printf("purged %d orphaned attachments", n)
+			}
+			time.Sleep(purgeInterval)
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+// Package storage keeps the bytes of note attachments. Metadata lives in
+// the database; a BlobStore only maps opaque keys to content.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores attachment content under keys chosen by the caller.
+type BlobStore interface {
+	// Put stores r under key. size is -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the content under key, seekable for Range requests.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by stores that let clients transfer content
+// directly with short-lived signed URLs instead of through the backend.
+type Presigner interface {
+	// PresignGet returns a URL serving key with the given response headers.
+	PresignGet(ctx context.Context, key, contentType, disposition string, expiry time.Duration) (string, error)
+	// PresignPost returns a URL and the form fields of an HTTP POST upload
+	// of key. The store refuses content other than size bytes declared as
+	// contentType.
+	PresignPost(ctx context.Context, key, contentType string, size int64, expiry time.Duration) (string, map[string]string, error)
+	// Stat returns the size of the content under key.
+	Stat(ctx context.Context, key string) (int64, error)
+}
@@ -6,10 +6,15 @@ export default function NotePage() {
   const router = useRouter();
   const { id } = router.query;
   const [notes, setNotes] = useState([]);
+  const [nextCursor, setNextCursor] = useState(null);
   const [selectedNote, setSelectedNote] = useState(null);
   const [searchQuery, setSearchQuery] = useState('');
   const editorRef = useRef(null);
   const cursorPositionRef = useRef(null);
+  // Versi note di server, dikirim saat simpan agar edit bersamaan tidak saling timpa
+  const versionRef = useRef(null);
+  // Hanya edit dari pengguna yang memicu autosave, bukan saat note dimuat atau difavoritkan
+  const editedRef = useRef(false);
 
   // Load all notes on mount
   useEffect(() => {
@@ -18,20 +23,21 @@ export default function NotePage() {
       const data = await api('/api/notes');
 
       // Jika data null atau bukan array → anggap data kosong
-      if (!data || !Array.isArray(data)) {
+      if (!data || !Array.isArray(data.notes)) {
         console.warn('Response kosong dari server (null)');
         setNotes([]);
         return;
       }
 
-      if (data.length === 0) {
+      if (data.notes.length === 0) {
         console.log('Database tersambung, tapi belum ada note.');
         setNotes([]);
         return;
       }
 
       // Kalau ada data
-      setNotes(data);
+      setNotes(data.notes);
+      setNextCursor(data.next_cursor || null);
       } catch (err) {
         console.error('Failed to load notes:', err);
 
@@ -58,6 +64,30 @@ export default function NotePage() {
     loadNotes();
   }, []);
 
+  // Perubahan note dari server (SSE), jadi daftar tidak perlu di-fetch ulang
+  useEffect(() => {
+    const events = new EventSource('/api/events', { withCredentials: true });
+
+    const upsert = (e) => {
+      const { note } = JSON.parse(e.data);
+      setNotes((prev) => {
+        // Note yang diarsipkan tidak tampil di daftar default
+        if (note.archivedAt) return prev.filter(n => n.id !== note.id);
+        if (prev.some(n => n.id === note.id)) return prev.map(n => n.id === note.id ? { ...n, ...note } : n);
+        return [note, ...prev];
+      });
+    };
+    const remove = (e) => {
+      const { noteId } = JSON.parse(e.data);
+      setNotes((prev) => prev.filter(n => n.id !== noteId));
+    };
+
+    events.addEventListener('note.created', upsert);
+    events.addEventListener('note.updated', upsert);
+    events.addEventListener('note.deleted', remove);
+    return () => events.close();
+  }, []);
+
   // Load specific note when ID changes in URL
   useEffect(() => {
     if (!id || !notes.length) return;
@@ -65,6 +95,8 @@ export default function NotePage() {
     async function loadNoteById() {
       try {
         const noteDetail = await api(`/api/notes/${id}`);
+        versionRef.current = noteDetail.version;
+        editedRef.current = false;
         setSelectedNote(noteDetail);
       } catch (err) {
         console.error('Failed to fetch note detail:', err);
@@ -104,6 +136,18 @@ export default function NotePage() {
     }
   };
 
+  // Ambil halaman berikutnya dari daftar notes
+  const handleLoadMore = async () => {
+    if (!nextCursor) return;
+    try {
+      const data = await api(`/api/notes?cursor=${encodeURIComponent(nextCursor)}`);
+      setNotes((prev) => [...prev, ...data.notes]);
+      setNextCursor(data.next_cursor || null);
+    } catch (err) {
+      console.error('Failed to load more notes:', err);
+    }
+  };
+
   // Navigate to note by ID
   const handleSelectNote = (noteId) => {
     router.push(`/notes/${noteId}`);
@@ -180,6 +224,16 @@ export default function NotePage() {
     setNotes(notes.map(n => n.id === selectedNote.id ? updatedNote : n));
   };
 
+  // Viewer hanya bisa membaca; hanya editor dan pemilik yang bisa mengubah note
+  const canEdit = selectedNote?.role === 'editor' || selectedNote?.role === 'owner';
+
+  // Edit dari pengguna: tandai agar autosave menyimpannya
+  const editNoteField = (field, value) => {
+    if (!canEdit) return;
+    editedRef.current = true;
+    updateNoteField(field, value);
+  };
+
   // Restore cursor after content update
   useEffect(() => {
     if (editorRef.current && cursorPositionRef.current) {
@@ -187,13 +241,29 @@ export default function NotePage() {
     }
   }, [selectedNote?.content]);
 
+  // Favorite disimpan per pengguna, jadi pakai endpoint terpisah
+  const handleToggleFavorite = async () => {
+    if (!selectedNote) return;
+    try {
+      const res = await api(`/api/notes/${selectedNote.id}/favorite`, {
+        method: selectedNote.favorite ? 'DELETE' : 'PUT',
+      });
+      updateNoteField('favorite', res.favorite);
+    } catch (err) {
+      console.error('Failed to toggle favorite:', err);
+    }
+  };
+
   // Delete note
   const handleDeleteNote = async () => {
     if (!selectedNote) return;
-    if (!confirm('Yakin ingin hapus note ini?')) return;
+    if (!confirm('Pindahkan note ini ke tempat sampah?')) return;
 
     try {
-      await api(`/api/notes/${selectedNote.id}`, { method: 'DELETE' });
+      await api(`/api/notes/${selectedNote.id}`, {
+        method: 'DELETE',
+        headers: { 'If-Match': `"${versionRef.current}"` },
+      });
       const newNotes = notes.filter(n => n.id !== selectedNote.id);
       setNotes(newNotes);
       // Navigate to first note or back to /notes
@@ -215,6 +285,8 @@ export default function NotePage() {
 
   // Helpers
   const stripHtml = (html) => html ? html.replace(/<[^>]*>/g, '') : '';
+  // Daftar dari server hanya membawa excerpt; note yang sedang diedit membawa content penuh
+  const getNotePreview = (note) => note.excerpt ?? stripHtml(note.content);
   const getWordCount = () => {
     const text = editorRef.current ? (editorRef.current.textContent || '') : (selectedNote?.content ? stripHtml(selectedNote.content) : '');
     return text.trim().split(/\s+/).filter(w => w.length > 0).length;
@@ -224,27 +296,42 @@ export default function NotePage() {
   const filteredNotes = notes.filter(note => {
     const searchLower = searchQuery.toLowerCase();
     return note.title.toLowerCase().includes(searchLower) || 
-           note.content.toLowerCase().includes(searchLower);
+           getNotePreview(note).toLowerCase().includes(searchLower);
   });
 
   // Autosave ke server saat note berubah
   useEffect(() => {
-    if (!selectedNote || !selectedNote.id) return;
+    if (!selectedNote || !selectedNote.id || !canEdit || !editedRef.current) return;
 
     const timeout = setTimeout(async () => {
+      editedRef.current = false;
       try {
-        await api(`/api/notes/${selectedNote.id}`, {
+        const res = await api(`/api/notes/${selectedNote.id}`, {
           method: 'PUT',
           body: JSON.stringify({
             title: selectedNote.title,
             content: selectedNote.content,
             shared: selectedNote.shared,
             favorite: selectedNote.favorite,
+            version: versionRef.current,
           }),
         });
+        versionRef.current = res.version;
+        // Server menggabungkan edit kita dengan perubahan orang lain
+        if (res.message === 'merged') {
+          setSelectedNote((prev) => ({ ...prev, title: res.title, content: res.content }));
+        }
         console.log('Note saved!');
       } catch (err) {
         console.error('Failed to save note:', err);
+        // 412/409: note sudah diubah orang lain dan tidak bisa digabung → pakai salinan terbaru dari server
+        if (err.message.includes('version mismatch') || err.message.includes('merge conflict')) {
+          const { current } = JSON.parse(err.message);
+          alert('Note ini baru saja diubah oleh pengguna lain. Memuat versi terbaru.');
+          versionRef.current = current.version;
+          setSelectedNote(current);
+          setNotes((prev) => prev.map(n => n.id === current.id ? current : n));
+        }
       }
     }, 800); // delay 0.8 detik biar gak spam
 
@@ -288,7 +375,7 @@ export default function NotePage() {
                       @{note.ownerUsername || 'unknown'}
                     </span>
                   </div>
-                  <p className="text-xs text-gray-400 line-clamp-2 mb-2">{stripHtml(note.content)}</p>
+                  <p className="text-xs text-gray-400 line-clamp-2 mb-2">{getNotePreview(note)}</p>
                   <div className="flex items-center gap-2">
                     <span className="text-[10px] text-gray-500">
                       {new Date(note.updatedAt).toLocaleDateString()}
@@ -296,6 +383,14 @@ export default function NotePage() {
                   </div>
                 </button>
               ))}
+              {nextCursor && (
+                <button
+                  onClick={handleLoadMore}
+                  className="w-full py-3 text-xs text-gray-400 hover:text-white hover:bg-white/5 transition-all"
+                >
+                  Load more
+                </button>
+              )}
             </div>
           </div>
         </div>
@@ -308,7 +403,7 @@ export default function NotePage() {
               <div className="px-8 py-4 border-b flex items-center justify-between" style={{ background: 'rgba(14, 33, 72, 0.4)', borderColor: 'rgba(227, 208, 149, 0.15)' }}>
                 <div className="flex items-center gap-3">
                   <button
-                    onClick={() => updateNoteField('favorite', !selectedNote.favorite)}
+                    onClick={handleToggleFavorite}
                     className="p-2 rounded-lg hover:bg-white/10 transition-all"
                   >
                     <svg className={`w-5 h-5 ${selectedNote.favorite ? 'text-yellow-400 fill-current' : 'text-gray-400'}`} fill="none" stroke="currentColor" viewBox="0 0 24 24">
@@ -344,7 +439,8 @@ export default function NotePage() {
                   <input
                     type="text"
                     value={selectedNote.title}
-                    onChange={(e) => updateNoteField('title', e.target.value)}
+                    onChange={(e) => editNoteField('title', e.target.value)}
+                    readOnly={!canEdit}
                     placeholder="Untitled Note"
                     className="w-full text-4xl font-bold text-white bg-transparent border-none outline-none placeholder-gray-600 pb-4"
                   />
@@ -356,7 +452,7 @@ export default function NotePage() {
                   <div
                     ref={editorRef}
                     id="note-content"
-                    contentEditable
+                    contentEditable={canEdit}
                     suppressContentEditableWarning
                     onKeyDown={(e) => {
                       if (e.key === 'Enter') {
@@ -366,7 +462,7 @@ export default function NotePage() {
                     }}
                     onInput={(e) => {
                       saveCursorPosition();
-                      updateNoteField('content', e.currentTarget.innerHTML);
+                      editNoteField('content', e.currentTarget.innerHTML);
                     }}
                     className="w-full min-h-[500px] text-lg text-gray-300 bg-transparent border-none outline-none placeholder-gray-600 resize-none leading-relaxed"
                     style={{
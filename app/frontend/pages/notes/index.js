@@ -10,11 +10,11 @@ export default function NotesIndex() {
   useEffect(() => {
     async function checkNotes() {
       try {
-        const data = await api('/api/notes');
+        const data = await api('/api/notes?limit=1');
         
-        if (data && Array.isArray(data) && data.length > 0) {
+        if (data && Array.isArray(data.notes) && data.notes.length > 0) {
           // Redirect to first note
-          router.replace(`/notes/${data[0].id}`);
+          router.replace(`/notes/${data.notes[0].id}`);
         } else {
           // No notes, show empty state
           setHasNotes(false);